@@ -0,0 +1,235 @@
+package android
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Binary XML chunk types (see ResourceTypes.h of the Android framework)
+const (
+	chunkStringPool      = 0x0001
+	chunkXML             = 0x0003
+	chunkXMLStartElement = 0x0102
+	chunkXMLEndElement   = 0x0103
+	chunkXMLResourceMap  = 0x0180
+)
+
+// Res_value data types
+const (
+	typeReference  = 0x01
+	typeString     = 0x03
+	typeIntDec     = 0x10
+	typeIntHex     = 0x11
+	typeIntBoolean = 0x12
+)
+
+const (
+	noEntry          = 0xFFFFFFFF
+	stringPoolUTF8   = 1 << 8
+	attributeMinSize = 20
+)
+
+var errTruncated = errors.New("truncated binary XML")
+
+// parseAXML reads the binary XML (as in the AndroidManifest.xml of an APK) and returns its root element
+func parseAXML(data []byte) (*Element, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != chunkXML {
+		return nil, errors.New("not a binary XML document")
+	}
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	size := int(binary.LittleEndian.Uint32(data[4:]))
+	if size > len(data) {
+		return nil, errTruncated
+	}
+
+	var pool []string
+	var resourceIDs []uint32
+	var root *Element
+	var stack []*Element
+	for offset+8 <= size {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		headerSize := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if chunkSize < 8 || headerSize > chunkSize || offset+chunkSize > size {
+			return nil, errTruncated
+		}
+		chunk := data[offset : offset+chunkSize]
+
+		switch chunkType {
+		case chunkStringPool:
+			var err error
+			if pool, err = parseStringPool(chunk, headerSize); err != nil {
+				return nil, err
+			}
+		case chunkXMLResourceMap:
+			for i := headerSize; i+4 <= chunkSize; i += 4 {
+				resourceIDs = append(resourceIDs, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+		case chunkXMLStartElement:
+			element, err := parseStartElement(chunk, headerSize, pool, resourceIDs)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, element)
+			} else if root == nil {
+				root = element
+			}
+			stack = append(stack, element)
+		case chunkXMLEndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		offset += chunkSize
+	}
+
+	if root == nil {
+		return nil, errors.New("no element in the binary XML document")
+	}
+	return root, nil
+}
+
+func parseStringPool(chunk []byte, headerSize int) ([]string, error) {
+	if headerSize < 28 {
+		return nil, errTruncated
+	}
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	flags := binary.LittleEndian.Uint32(chunk[16:])
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	if headerSize+4*count > len(chunk) || stringsStart > len(chunk) {
+		return nil, errTruncated
+	}
+
+	pool := make([]string, count)
+	for i := range pool {
+		pos := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+4*i:]))
+		var s string
+		var err error
+		if flags&stringPoolUTF8 != 0 {
+			s, err = decodeUTF8String(chunk, pos)
+		} else {
+			s, err = decodeUTF16String(chunk, pos)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pool[i] = s
+	}
+	return pool, nil
+}
+
+// decodeUTF8String reads a string of an UTF-8 pool: the UTF-16 length, the UTF-8 length (both 1 or 2 bytes) and the bytes
+func decodeUTF8String(chunk []byte, pos int) (string, error) {
+	readLength := func() (int, error) {
+		if pos >= len(chunk) {
+			return 0, errTruncated
+		}
+		length := int(chunk[pos])
+		pos++
+		if length&0x80 != 0 {
+			if pos >= len(chunk) {
+				return 0, errTruncated
+			}
+			length = (length&0x7F)<<8 | int(chunk[pos])
+			pos++
+		}
+		return length, nil
+	}
+
+	if _, err := readLength(); err != nil {
+		return "", err
+	}
+	length, err := readLength()
+	if err != nil {
+		return "", err
+	}
+	if pos+length > len(chunk) {
+		return "", errTruncated
+	}
+	return string(chunk[pos : pos+length]), nil
+}
+
+// decodeUTF16String reads a string of an UTF-16 pool: the length in code units (2 or 4 bytes) and the code units
+func decodeUTF16String(chunk []byte, pos int) (string, error) {
+	if pos+2 > len(chunk) {
+		return "", errTruncated
+	}
+	length := int(binary.LittleEndian.Uint16(chunk[pos:]))
+	pos += 2
+	if length&0x8000 != 0 {
+		if pos+2 > len(chunk) {
+			return "", errTruncated
+		}
+		length = (length&0x7FFF)<<16 | int(binary.LittleEndian.Uint16(chunk[pos:]))
+		pos += 2
+	}
+	if pos+2*length > len(chunk) {
+		return "", errTruncated
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(chunk[pos+2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+func parseStartElement(chunk []byte, headerSize int, pool []string, resourceIDs []uint32) (*Element, error) {
+	if headerSize+20 > len(chunk) {
+		return nil, errTruncated
+	}
+	ext := chunk[headerSize:]
+	str := func(index uint32) string {
+		if index == noEntry || int(index) >= len(pool) {
+			return ""
+		}
+		return pool[index]
+	}
+
+	element := &Element{Name: str(binary.LittleEndian.Uint32(ext[4:]))}
+	attrStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attrSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attrCount := int(binary.LittleEndian.Uint16(ext[12:]))
+	if attrSize < attributeMinSize || attrStart+attrSize*attrCount > len(ext) {
+		return nil, errTruncated
+	}
+
+	for i := 0; i < attrCount; i++ {
+		raw := ext[attrStart+i*attrSize:]
+		nameIndex := binary.LittleEndian.Uint32(raw[4:])
+		attr := Attr{
+			Namespace: str(binary.LittleEndian.Uint32(raw)),
+			Name:      str(nameIndex),
+		}
+		if int(nameIndex) < len(resourceIDs) {
+			attr.ResourceID = resourceIDs[nameIndex]
+		}
+
+		rawValue := binary.LittleEndian.Uint32(raw[8:])
+		dataType := raw[15]
+		data := binary.LittleEndian.Uint32(raw[16:])
+		switch {
+		case rawValue != noEntry:
+			attr.Value = str(rawValue)
+		case dataType == typeString:
+			attr.Value = str(data)
+		case dataType == typeIntDec:
+			attr.Value = strconv.Itoa(int(int32(data)))
+		case dataType == typeIntHex:
+			attr.Value = fmt.Sprintf("0x%x", data)
+		case dataType == typeIntBoolean:
+			attr.Value = strconv.FormatBool(data != 0)
+		case dataType == typeReference:
+			attr.Value = fmt.Sprintf("@0x%08x", data)
+		default:
+			attr.Value = strconv.FormatUint(uint64(data), 10)
+		}
+		element.Attrs = append(element.Attrs, attr)
+	}
+	return element, nil
+}
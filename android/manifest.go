@@ -0,0 +1,136 @@
+package android
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+)
+
+// Resource ids of the android namespace attributes (see android.R.attr)
+const (
+	attrVersionCode      = 0x0101021b
+	attrVersionName      = 0x0101021c
+	attrMinSdkVersion    = 0x0101020c
+	attrTargetSdkVersion = 0x01010270
+	attrName             = 0x01010003
+	attrDebuggable       = 0x0101000f
+)
+
+// Manifest entries of the app bundles
+const (
+	apkManifestEntry = "AndroidManifest.xml"
+	aabManifestEntry = "base/manifest/AndroidManifest.xml"
+)
+
+// Manifest is the subset of the AndroidManifest.xml of an APK or an AAB used by the step
+type Manifest struct {
+	PackageName string
+	VersionCode int
+	// VersionName is empty if it is a reference to a string resource
+	VersionName string
+	MinSDK      int
+	TargetSDK   int
+	// Permissions are the requested permissions (uses-permission and uses-permission-sdk-23)
+	Permissions []string
+	// Debuggable is the android:debuggable of the application
+	Debuggable bool
+}
+
+// ReadManifest reads the compiled AndroidManifest.xml of the APK (binary XML) or the AAB (protobuf XML)
+func ReadManifest(pth string) (Manifest, error) {
+	entry, parse := apkManifestEntry, parseAXML
+	if filepath.Ext(pth) == ".aab" {
+		entry, parse = aabManifestEntry, parseProtoXML
+	}
+
+	data, err := readZipEntry(pth, entry)
+	if err != nil {
+		return Manifest{}, err
+	}
+	root, err := parse(data)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse %s of %s: %s", entry, pth, err)
+	}
+	return manifestFromElement(root)
+}
+
+func readZipEntry(pth, name string) ([]byte, error) {
+	r, err := zip.OpenReader(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = rc.Close()
+		}()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("%s not found in %s", name, pth)
+}
+
+func manifestFromElement(root *Element) (Manifest, error) {
+	if root.Name != "manifest" {
+		return Manifest{}, fmt.Errorf("unexpected root element: %s", root.Name)
+	}
+
+	var manifest Manifest
+	manifest.PackageName, _ = root.Attr("package", 0)
+	if versionCode, ok := root.Attr("versionCode", attrVersionCode); ok {
+		manifest.VersionCode = parseInt(versionCode)
+	}
+	if versionName, ok := root.Attr("versionName", attrVersionName); ok && !isReference(versionName) {
+		manifest.VersionName = versionName
+	}
+
+	// the defaults of the platform, if uses-sdk is missing
+	manifest.MinSDK = 1
+	for _, usesSDK := range root.ChildrenNamed("uses-sdk") {
+		if minSDK, ok := usesSDK.Attr("minSdkVersion", attrMinSdkVersion); ok {
+			manifest.MinSDK = parseInt(minSDK)
+		}
+		if targetSDK, ok := usesSDK.Attr("targetSdkVersion", attrTargetSdkVersion); ok {
+			manifest.TargetSDK = parseInt(targetSDK)
+		}
+	}
+	if manifest.TargetSDK == 0 {
+		manifest.TargetSDK = manifest.MinSDK
+	}
+
+	for _, child := range root.Children {
+		if child.Name != "uses-permission" && child.Name != "uses-permission-sdk-23" {
+			continue
+		}
+		if name, ok := child.Attr("name", attrName); ok && name != "" {
+			manifest.Permissions = append(manifest.Permissions, name)
+		}
+	}
+	for _, application := range root.ChildrenNamed("application") {
+		if debuggable, ok := application.Attr("debuggable", attrDebuggable); ok {
+			manifest.Debuggable = debuggable == "true"
+		}
+	}
+
+	return manifest, nil
+}
+
+// parseInt parses decimal and hexadecimal values, an unparsable value (like a preview SDK codename) results in 0
+func parseInt(value string) int {
+	i, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0
+	}
+	return int(i)
+}
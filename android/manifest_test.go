@@ -0,0 +1,295 @@
+package android
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+const androidNamespace = "http://schemas.android.com/apk/res/android"
+
+// testAttr is an attribute of the test documents, either a string or a typed (int, boolean, reference) value
+type testAttr struct {
+	name       string
+	resourceID uint32
+	str        string
+	dataType   uint8
+	data       uint32
+}
+
+type testElement struct {
+	name     string
+	attrs    []testAttr
+	children []testElement
+}
+
+var testManifest = testElement{
+	name: "manifest",
+	attrs: []testAttr{
+		{name: "versionCode", resourceID: attrVersionCode, dataType: typeIntDec, data: 10203},
+		{name: "versionName", resourceID: attrVersionName, str: "1.2.3"},
+		{name: "package", str: "io.ionic.starter"},
+	},
+	children: []testElement{
+		{name: "uses-sdk", attrs: []testAttr{
+			{name: "minSdkVersion", resourceID: attrMinSdkVersion, dataType: typeIntDec, data: 22},
+			{name: "targetSdkVersion", resourceID: attrTargetSdkVersion, dataType: typeIntDec, data: 33},
+		}},
+		{name: "uses-permission", attrs: []testAttr{{name: "name", resourceID: attrName, str: "android.permission.INTERNET"}}},
+		{name: "uses-permission-sdk-23", attrs: []testAttr{{name: "name", resourceID: attrName, str: "android.permission.CAMERA"}}},
+		{name: "application", attrs: []testAttr{
+			{name: "debuggable", resourceID: attrDebuggable, dataType: typeIntBoolean, data: 0xFFFFFFFF},
+		}},
+	},
+}
+
+// buildAXML encodes the element as binary XML, the attribute names with a resource id are placed at the start of the string pool
+func buildAXML(root testElement, utf8 bool, stripNames bool) []byte {
+	var pool []string
+	index := map[string]uint32{}
+	add := func(s string) uint32 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = uint32(len(pool))
+		pool = append(pool, s)
+		return index[s]
+	}
+
+	var resourceIDs []uint32
+	var collectAttrNames func(e testElement)
+	collectAttrNames = func(e testElement) {
+		for _, attr := range e.attrs {
+			if attr.resourceID == 0 {
+				continue
+			}
+			// a shrinker may strip the names, the resource map still identifies the attributes
+			name := attr.name
+			if stripNames {
+				name = ""
+			}
+			if _, ok := index[name]; !ok || stripNames {
+				pool = append(pool, name)
+				index[name] = uint32(len(pool) - 1)
+				resourceIDs = append(resourceIDs, attr.resourceID)
+			}
+		}
+		for _, child := range e.children {
+			collectAttrNames(child)
+		}
+	}
+	collectAttrNames(root)
+	nameIndexByID := map[uint32]uint32{}
+	for i, id := range resourceIDs {
+		nameIndexByID[id] = uint32(i)
+	}
+	ns := add(androidNamespace)
+
+	var body bytes.Buffer
+	var writeElement func(e testElement)
+	writeElement = func(e testElement) {
+		name := add(e.name)
+
+		var attrs bytes.Buffer
+		for _, attr := range e.attrs {
+			nameIndex, ok := nameIndexByID[attr.resourceID]
+			if !ok {
+				nameIndex = add(attr.name)
+			}
+			rawValue, dataType, data := uint32(noEntry), attr.dataType, attr.data
+			if attr.dataType == 0 {
+				rawValue = add(attr.str)
+				dataType, data = typeString, rawValue
+			}
+			write(&attrs, ns, nameIndex, rawValue, uint16(8), uint8(0), dataType, data)
+		}
+
+		write(&body, uint16(chunkXMLStartElement), uint16(16), uint32(16+20+attrs.Len()), uint32(1), uint32(noEntry))
+		write(&body, uint32(noEntry), name, uint16(20), uint16(20), uint16(len(e.attrs)), uint16(0), uint16(0), uint16(0))
+		body.Write(attrs.Bytes())
+		for _, child := range e.children {
+			writeElement(child)
+		}
+		write(&body, uint16(chunkXMLEndElement), uint16(16), uint32(24), uint32(1), uint32(noEntry), uint32(noEntry), name)
+	}
+	writeElement(root)
+
+	var stringData bytes.Buffer
+	var offsets []uint32
+	for _, s := range pool {
+		offsets = append(offsets, uint32(stringData.Len()))
+		if utf8 {
+			write(&stringData, uint8(len(utf16.Encode([]rune(s)))), uint8(len(s)))
+			stringData.WriteString(s)
+			stringData.WriteByte(0)
+		} else {
+			units := utf16.Encode([]rune(s))
+			write(&stringData, uint16(len(units)), units, uint16(0))
+		}
+	}
+	for stringData.Len()%4 != 0 {
+		stringData.WriteByte(0)
+	}
+
+	var flags uint32
+	if utf8 {
+		flags = stringPoolUTF8
+	}
+	var doc bytes.Buffer
+	stringsStart := uint32(28 + 4*len(pool))
+	write(&doc, uint16(chunkStringPool), uint16(28), stringsStart+uint32(stringData.Len()), uint32(len(pool)), uint32(0), flags, stringsStart, uint32(0), offsets)
+	doc.Write(stringData.Bytes())
+	write(&doc, uint16(chunkXMLResourceMap), uint16(8), uint32(8+4*len(resourceIDs)), resourceIDs)
+	doc.Write(body.Bytes())
+
+	var out bytes.Buffer
+	write(&out, uint16(chunkXML), uint16(8), uint32(8+doc.Len()))
+	out.Write(doc.Bytes())
+	return out.Bytes()
+}
+
+func write(buf *bytes.Buffer, values ...interface{}) {
+	for _, v := range values {
+		if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// buildProtoXML encodes the element as an aapt2 XmlNode, typed values are stored as compiled items
+func buildProtoXML(e testElement) []byte {
+	var element []byte
+	element = append(element, protoBytes(3, []byte(e.name))...)
+	for _, attr := range e.attrs {
+		var a []byte
+		if attr.resourceID != 0 {
+			a = append(a, protoBytes(1, []byte(androidNamespace))...)
+		}
+		a = append(a, protoBytes(2, []byte(attr.name))...)
+		if attr.resourceID != 0 {
+			a = append(a, protoVarint(5, uint64(attr.resourceID))...)
+		}
+
+		switch attr.dataType {
+		case 0:
+			a = append(a, protoBytes(3, []byte(attr.str))...)
+		case typeIntDec:
+			a = append(a, protoBytes(6, protoBytes(7, protoVarint(6, uint64(attr.data))))...)
+		case typeIntBoolean:
+			a = append(a, protoBytes(6, protoBytes(7, protoVarint(8, 1)))...)
+		case typeReference:
+			a = append(a, protoBytes(6, protoBytes(1, protoVarint(2, uint64(attr.data))))...)
+		}
+		element = append(element, protoBytes(4, a)...)
+	}
+	for _, child := range e.children {
+		element = append(element, protoBytes(5, buildProtoXML(child))...)
+	}
+	// a text node child
+	element = append(element, protoBytes(5, protoBytes(2, []byte("\n")))...)
+	return protoBytes(1, element)
+}
+
+func protoVarint(field int, v uint64) []byte {
+	return append(uvarint(uint64(field<<3|wireVarint)), uvarint(v)...)
+}
+
+func protoBytes(field int, b []byte) []byte {
+	out := append(uvarint(uint64(field<<3|wireBytes)), uvarint(uint64(len(b)))...)
+	return append(out, b...)
+}
+
+func uvarint(v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, v)]
+}
+
+func writeZip(t *testing.T, pth, entry string, content []byte) {
+	f, err := os.Create(pth)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	entryWriter, err := w.Create(entry)
+	require.NoError(t, err)
+	_, err = entryWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func Test_ReadManifest(t *testing.T) {
+	referencedVersionName := testManifest
+	referencedVersionName.attrs = []testAttr{
+		{name: "versionCode", resourceID: attrVersionCode, dataType: typeIntHex, data: 0x10},
+		{name: "versionName", resourceID: attrVersionName, dataType: typeReference, data: 0x7f0b0001},
+		{name: "package", str: "io.ionic.starter"},
+	}
+	referencedVersionName.children = nil
+
+	want := Manifest{
+		PackageName: "io.ionic.starter",
+		VersionCode: 10203,
+		VersionName: "1.2.3",
+		MinSDK:      22,
+		TargetSDK:   33,
+		Permissions: []string{"android.permission.INTERNET", "android.permission.CAMERA"},
+		Debuggable:  true,
+	}
+	tests := []struct {
+		name    string
+		file    string
+		entry   string
+		content []byte
+		want    Manifest
+		wantErr bool
+	}{
+		{name: "apk with UTF-16 strings", file: "app.apk", entry: apkManifestEntry, content: buildAXML(testManifest, false, false), want: want},
+		{name: "apk with UTF-8 strings", file: "app.apk", entry: apkManifestEntry, content: buildAXML(testManifest, true, false), want: want},
+		{name: "apk with stripped attribute names", file: "app.apk", entry: apkManifestEntry, content: buildAXML(testManifest, false, true), want: want},
+		{
+			name:    "apk without uses-sdk and with a referenced version name",
+			file:    "app.apk",
+			entry:   apkManifestEntry,
+			content: buildAXML(referencedVersionName, false, false),
+			want:    Manifest{PackageName: "io.ionic.starter", VersionCode: 16, MinSDK: 1, TargetSDK: 1},
+		},
+		{name: "aab", file: "app.aab", entry: aabManifestEntry, content: buildProtoXML(testManifest), want: want},
+		{
+			name:    "aab with a referenced version name",
+			file:    "app.aab",
+			entry:   aabManifestEntry,
+			content: buildProtoXML(referencedVersionName),
+			want:    Manifest{PackageName: "io.ionic.starter", MinSDK: 1, TargetSDK: 1},
+		},
+		{name: "missing manifest", file: "app.apk", entry: "classes.dex", content: []byte("dex"), wantErr: true},
+		{name: "truncated binary XML", file: "app.apk", entry: apkManifestEntry, content: buildAXML(testManifest, false, false)[:100], wantErr: true},
+		{name: "text XML", file: "app.apk", entry: apkManifestEntry, content: []byte("<manifest/>"), wantErr: true},
+		{name: "truncated protobuf XML", file: "app.aab", entry: aabManifestEntry, content: buildProtoXML(testManifest)[:50], wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), tt.file)
+			writeZip(t, pth, tt.entry, tt.content)
+
+			got, err := ReadManifest(pth)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_PermissionName(t *testing.T) {
+	require.Equal(t, "android.permission.CAMERA", PermissionName("CAMERA"))
+	require.Equal(t, "com.android.voicemail.permission.ADD_VOICEMAIL", PermissionName("com.android.voicemail.permission.ADD_VOICEMAIL"))
+	require.True(t, IsDangerousPermission(PermissionName("CAMERA")))
+	require.False(t, IsDangerousPermission(PermissionName("INTERNET")))
+}
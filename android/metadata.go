@@ -0,0 +1,120 @@
+// Package android reads the metadata of the Android build outputs
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// OutputMetadataFileName is the file the Android Gradle Plugin (4.1+) writes next to the APKs of a variant
+const OutputMetadataFileName = "output-metadata.json"
+
+// OutputMetadata is the content of output-metadata.json
+type OutputMetadata struct {
+	Version       int             `json:"version"`
+	ApplicationID string          `json:"applicationId"`
+	VariantName   string          `json:"variantName"`
+	Elements      []OutputElement `json:"elements"`
+}
+
+// OutputElement describes an APK of the variant
+type OutputElement struct {
+	// Type is SINGLE, or ONE_OF_MANY for split APKs
+	Type        string         `json:"type"`
+	Filters     []OutputFilter `json:"filters"`
+	VersionCode int            `json:"versionCode"`
+	VersionName string         `json:"versionName"`
+	// OutputFile is relative to the metadata's directory
+	OutputFile string `json:"outputFile"`
+}
+
+// OutputFilter is a split filter of an APK, like {"filterType": "ABI", "value": "arm64-v8a"}
+type OutputFilter struct {
+	FilterType string `json:"filterType"`
+	Value      string `json:"value"`
+}
+
+// ABIs returns the ABI filter values of the APK, an APK without ABI filters supports every ABI of the app
+func (e OutputElement) ABIs() []string {
+	var abis []string
+	for _, filter := range e.Filters {
+		if filter.FilterType == "ABI" {
+			abis = append(abis, filter.Value)
+		}
+	}
+	return abis
+}
+
+// ReadOutputMetadata reads the output metadata in the dir, it returns nil if the dir has no output metadata
+func ReadOutputMetadata(dir string) (*OutputMetadata, error) {
+	pth := filepath.Join(dir, OutputMetadataFileName)
+	content, err := os.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var metadata OutputMetadata
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", pth, err)
+	}
+	return &metadata, nil
+}
+
+// Variant describes an APK based on the output metadata
+type Variant struct {
+	ApplicationID string
+	Name          string
+	VersionCode   int
+	VersionName   string
+	ABIs          []string
+}
+
+// ResolveVariants reads the output metadata in the directories of the APKs,
+// and returns the variants of the APKs listed in the metadata and the APKs not listed in the metadata of their directory (for example leftovers of an earlier build).
+// The APKs in a directory without output metadata (written by an older Android Gradle Plugin) have no variant, but they are not excluded.
+func ResolveVariants(apks []string) (map[string]Variant, []string, error) {
+	byDir := map[string][]string{}
+	for _, apk := range apks {
+		dir := filepath.Dir(apk)
+		byDir[dir] = append(byDir[dir], apk)
+	}
+
+	variants := map[string]Variant{}
+	var excluded []string
+	for dir, dirAPKs := range byDir {
+		metadata, err := ReadOutputMetadata(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if metadata == nil {
+			continue
+		}
+
+		listed := map[string]Variant{}
+		for _, element := range metadata.Elements {
+			listed[filepath.Join(dir, element.OutputFile)] = Variant{
+				ApplicationID: metadata.ApplicationID,
+				Name:          metadata.VariantName,
+				VersionCode:   element.VersionCode,
+				VersionName:   element.VersionName,
+				ABIs:          element.ABIs(),
+			}
+		}
+
+		for _, apk := range dirAPKs {
+			if variant, ok := listed[apk]; ok {
+				variants[apk] = variant
+			} else {
+				excluded = append(excluded, apk)
+			}
+		}
+	}
+
+	sort.Strings(excluded)
+	return variants, excluded, nil
+}
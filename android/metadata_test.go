@@ -0,0 +1,88 @@
+package android
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const splitMetadata = `{
+  "version": 3,
+  "artifactType": {"type": "APK", "kind": "Directory"},
+  "applicationId": "io.ionic.starter",
+  "variantName": "freeRelease",
+  "elements": [
+    {
+      "type": "ONE_OF_MANY",
+      "filters": [{"filterType": "ABI", "value": "arm64-v8a"}],
+      "attributes": [],
+      "versionCode": 10002,
+      "versionName": "1.0.0",
+      "outputFile": "app-free-arm64-v8a-release.apk"
+    },
+    {
+      "type": "ONE_OF_MANY",
+      "filters": [],
+      "attributes": [],
+      "versionCode": 10000,
+      "versionName": "1.0.0",
+      "outputFile": "app-free-universal-release.apk"
+    }
+  ],
+  "elementType": "File"
+}`
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		pth := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(pth), 0755))
+		require.NoError(t, os.WriteFile(pth, []byte(content), 0600))
+	}
+}
+
+func Test_ReadOutputMetadata(t *testing.T) {
+	dir := t.TempDir()
+
+	metadata, err := ReadOutputMetadata(dir)
+	require.NoError(t, err)
+	require.Nil(t, metadata)
+
+	writeFiles(t, dir, map[string]string{OutputMetadataFileName: splitMetadata})
+	metadata, err = ReadOutputMetadata(dir)
+	require.NoError(t, err)
+	require.Equal(t, "freeRelease", metadata.VariantName)
+	require.Len(t, metadata.Elements, 2)
+	require.Equal(t, []string{"arm64-v8a"}, metadata.Elements[0].ABIs())
+	require.Empty(t, metadata.Elements[1].ABIs())
+
+	writeFiles(t, dir, map[string]string{OutputMetadataFileName: `{`})
+	_, err = ReadOutputMetadata(dir)
+	require.Error(t, err)
+}
+
+func Test_ResolveVariants(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"free/release/" + OutputMetadataFileName:      splitMetadata,
+		"free/release/app-free-arm64-v8a-release.apk": "apk",
+		"free/release/app-free-universal-release.apk": "apk",
+		"free/release/app-free-release-unsigned.apk":  "leftover",
+		"legacy/app-release.apk":                      "apk",
+	})
+
+	apks := []string{
+		filepath.Join(dir, "free/release/app-free-arm64-v8a-release.apk"),
+		filepath.Join(dir, "free/release/app-free-release-unsigned.apk"),
+		filepath.Join(dir, "free/release/app-free-universal-release.apk"),
+		filepath.Join(dir, "legacy/app-release.apk"),
+	}
+	variants, excluded, err := ResolveVariants(apks)
+	require.NoError(t, err)
+	require.Equal(t, []string{apks[1]}, excluded)
+	require.Equal(t, map[string]Variant{
+		apks[0]: {ApplicationID: "io.ionic.starter", Name: "freeRelease", VersionCode: 10002, VersionName: "1.0.0", ABIs: []string{"arm64-v8a"}},
+		apks[2]: {ApplicationID: "io.ionic.starter", Name: "freeRelease", VersionCode: 10000, VersionName: "1.0.0"},
+	}, variants)
+}
@@ -0,0 +1,195 @@
+package android
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// The AndroidManifest.xml of an AAB is an aapt2 XmlNode protobuf message (see Resources.proto of aapt2),
+// only the fields used by the step are decoded, so that no protobuf runtime is needed.
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// parseProtoXML reads the protobuf XmlNode (as in the base/manifest/AndroidManifest.xml of an AAB) and returns its root element
+func parseProtoXML(data []byte) (*Element, error) {
+	element, err := parseProtoNode(data)
+	if err != nil {
+		return nil, err
+	}
+	if element == nil {
+		return nil, errors.New("no element in the protobuf XML document")
+	}
+	return element, nil
+}
+
+// parseProtoNode decodes an XmlNode: element = 1, it returns nil for a text node
+func parseProtoNode(data []byte) (*Element, error) {
+	var element *Element
+	err := forEachField(data, func(field int, wireType int, value uint64, bytes []byte) error {
+		if field == 1 && wireType == wireBytes {
+			var err error
+			element, err = parseProtoElement(bytes)
+			return err
+		}
+		return nil
+	})
+	return element, err
+}
+
+// parseProtoElement decodes an XmlElement: name = 3, attribute = 4, child = 5
+func parseProtoElement(data []byte) (*Element, error) {
+	element := &Element{}
+	err := forEachField(data, func(field int, wireType int, value uint64, bytes []byte) error {
+		if wireType != wireBytes {
+			return nil
+		}
+		switch field {
+		case 3:
+			element.Name = string(bytes)
+		case 4:
+			attr, err := parseProtoAttribute(bytes)
+			if err != nil {
+				return err
+			}
+			element.Attrs = append(element.Attrs, attr)
+		case 5:
+			child, err := parseProtoNode(bytes)
+			if err != nil {
+				return err
+			}
+			if child != nil {
+				element.Children = append(element.Children, child)
+			}
+		}
+		return nil
+	})
+	return element, err
+}
+
+// parseProtoAttribute decodes an XmlAttribute: namespace_uri = 1, name = 2, value = 3, resource_id = 5, compiled_item = 6
+func parseProtoAttribute(data []byte) (Attr, error) {
+	var attr Attr
+	var compiled string
+	err := forEachField(data, func(field int, wireType int, value uint64, bytes []byte) error {
+		switch {
+		case field == 1 && wireType == wireBytes:
+			attr.Namespace = string(bytes)
+		case field == 2 && wireType == wireBytes:
+			attr.Name = string(bytes)
+		case field == 3 && wireType == wireBytes:
+			attr.Value = string(bytes)
+		case field == 5 && wireType == wireVarint:
+			attr.ResourceID = uint32(value)
+		case field == 6 && wireType == wireBytes:
+			var err error
+			compiled, err = parseProtoItem(bytes)
+			return err
+		}
+		return nil
+	})
+	if attr.Value == "" {
+		attr.Value = compiled
+	}
+	return attr, err
+}
+
+// parseProtoItem formats a compiled Item: ref = 1 (Reference.id = 2), str = 2 (String.value = 1), prim = 7
+func parseProtoItem(data []byte) (string, error) {
+	var formatted string
+	err := forEachField(data, func(field int, wireType int, value uint64, bytes []byte) error {
+		if wireType != wireBytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			return forEachField(bytes, func(field int, wireType int, value uint64, _ []byte) error {
+				if field == 2 && wireType == wireVarint {
+					formatted = fmt.Sprintf("@0x%08x", uint32(value))
+				}
+				return nil
+			})
+		case 2:
+			return forEachField(bytes, func(field int, wireType int, _ uint64, bytes []byte) error {
+				if field == 1 && wireType == wireBytes {
+					formatted = string(bytes)
+				}
+				return nil
+			})
+		case 7:
+			return forEachField(bytes, func(field int, wireType int, value uint64, _ []byte) error {
+				switch {
+				// int_decimal_value
+				case field == 6 && wireType == wireVarint:
+					formatted = strconv.Itoa(int(int32(value)))
+				// int_hexadecimal_value
+				case field == 7 && wireType == wireVarint:
+					formatted = fmt.Sprintf("0x%x", uint32(value))
+				// boolean_value
+				case field == 8 && wireType == wireVarint:
+					formatted = strconv.FormatBool(value != 0)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return formatted, err
+}
+
+// forEachField calls fn with the fields of the protobuf message, value is set for varint and fixed fields, bytes for length-delimited ones
+func forEachField(data []byte, fn func(field int, wireType int, value uint64, bytes []byte) error) error {
+	for pos := 0; pos < len(data); {
+		key, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return errors.New("invalid protobuf field key")
+		}
+		pos += n
+		field, wireType := int(key>>3), int(key&7)
+
+		var value uint64
+		var bytes []byte
+		switch wireType {
+		case wireVarint:
+			value, n = binary.Uvarint(data[pos:])
+			if n <= 0 {
+				return errors.New("invalid protobuf varint")
+			}
+			pos += n
+		case wireFixed64:
+			if pos+8 > len(data) {
+				return errors.New("truncated protobuf message")
+			}
+			value = binary.LittleEndian.Uint64(data[pos:])
+			pos += 8
+		case wireFixed32:
+			if pos+4 > len(data) {
+				return errors.New("truncated protobuf message")
+			}
+			value = uint64(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+		case wireBytes:
+			length, n := binary.Uvarint(data[pos:])
+			if n <= 0 || uint64(len(data)-pos-n) < length {
+				return errors.New("truncated protobuf message")
+			}
+			pos += n
+			bytes = data[pos : pos+int(length)]
+			pos += int(length)
+		default:
+			return fmt.Errorf("unsupported protobuf wire type: %d", wireType)
+		}
+
+		if err := fn(field, wireType, value, bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package android
+
+import "strings"
+
+// Element is an element of a compiled Android XML document,
+// the binary XML (AXML) of an APK and the protobuf XML of an AAB are both read into Elements.
+type Element struct {
+	Name     string
+	Attrs    []Attr
+	Children []*Element
+}
+
+// Attr is an attribute of an Element.
+// The attribute names may be stripped by resource shrinkers, so the attributes of the android namespace are matched by their resource id.
+type Attr struct {
+	Namespace  string
+	Name       string
+	ResourceID uint32
+	// Value is the string form of the attribute value, references are formatted as @0x<resource id>
+	Value string
+}
+
+// Attr returns the value of the attribute, matching by the resource id if both the attribute and the lookup have one
+func (e *Element) Attr(name string, resourceID uint32) (string, bool) {
+	for _, attr := range e.Attrs {
+		if resourceID != 0 && attr.ResourceID != 0 {
+			if attr.ResourceID == resourceID {
+				return attr.Value, true
+			}
+			continue
+		}
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// ChildrenNamed returns the direct children with the name
+func (e *Element) ChildrenNamed(name string) []*Element {
+	var children []*Element
+	for _, child := range e.Children {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func isReference(value string) bool {
+	return strings.HasPrefix(value, "@")
+}
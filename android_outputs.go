@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/android"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const (
+	androidVariantEnvKey     = "BITRISE_ANDROID_VARIANT"
+	androidVersionCodeEnvKey = "BITRISE_ANDROID_VERSION_CODE"
+	androidVersionNameEnvKey = "BITRISE_ANDROID_VERSION_NAME"
+	androidABIFiltersEnvKey  = "BITRISE_ANDROID_ABI_FILTERS"
+
+	androidPackageNameEnvKey      = "BITRISE_ANDROID_PACKAGE_NAME"
+	androidMinSDKVersionEnvKey    = "BITRISE_ANDROID_MIN_SDK_VERSION"
+	androidTargetSDKVersionEnvKey = "BITRISE_ANDROID_TARGET_SDK_VERSION"
+)
+
+// resolveAndroidVariants maps the APKs to their variants based on the Gradle output metadata,
+// the APKs not listed in the output metadata of their directory are dropped.
+func resolveAndroidVariants(apks []string) ([]string, map[string]android.Variant) {
+	variants, excluded, err := android.ResolveVariants(apks)
+	if err != nil {
+		log.Warnf("Failed to read the Gradle output metadata, error: %s", err)
+		return apks, nil
+	}
+
+	var kept []string
+	for _, apk := range apks {
+		isExcluded := false
+		for _, pth := range excluded {
+			if pth == apk {
+				isExcluded = true
+				break
+			}
+		}
+		if isExcluded {
+			log.Warnf("%s is not listed in %s, skipping it", apk, android.OutputMetadataFileName)
+			continue
+		}
+		kept = append(kept, apk)
+	}
+	return kept, variants
+}
+
+// readAndroidManifests reads the AndroidManifest.xml of the exported APKs or AABs and records them in the artifact manifest,
+// an unreadable manifest is reported, but it does not fail the step.
+func readAndroidManifests(manifest *artifactManifest, deployedPaths []string) map[string]android.Manifest {
+	androidManifests := map[string]android.Manifest{}
+	for _, pth := range deployedPaths {
+		androidManifest, err := android.ReadManifest(pth)
+		if err != nil {
+			log.Warnf("Failed to read the AndroidManifest.xml of %s, error: %s", pth, err)
+			continue
+		}
+		androidManifests[pth] = androidManifest
+		manifest.setAndroidManifest(pth, androidManifest)
+	}
+	return androidManifests
+}
+
+// exportAndroidArtifact exports the details of the last exported APK or AAB:
+// the variant and the ABI filters (separated via ,) from the Gradle output metadata,
+// the package name, the version and the SDK levels from its AndroidManifest.xml.
+func exportAndroidArtifact(r runner.Runner, variant *android.Variant, androidManifest *android.Manifest) error {
+	type env struct{ name, key, value string }
+	var envs []env
+	if variant != nil {
+		envs = append(envs,
+			env{"variant", androidVariantEnvKey, variant.Name},
+			env{"ABI filters", androidABIFiltersEnvKey, strings.Join(variant.ABIs, ",")},
+		)
+	}
+	switch {
+	case androidManifest != nil:
+		envs = append(envs,
+			env{"package name", androidPackageNameEnvKey, androidManifest.PackageName},
+			env{"version code", androidVersionCodeEnvKey, strconv.Itoa(androidManifest.VersionCode)},
+			env{"version name", androidVersionNameEnvKey, androidManifest.VersionName},
+			env{"min SDK version", androidMinSDKVersionEnvKey, strconv.Itoa(androidManifest.MinSDK)},
+			env{"target SDK version", androidTargetSDKVersionEnvKey, strconv.Itoa(androidManifest.TargetSDK)},
+		)
+	case variant != nil:
+		envs = append(envs,
+			env{"version code", androidVersionCodeEnvKey, strconv.Itoa(variant.VersionCode)},
+			env{"version name", androidVersionNameEnvKey, variant.VersionName},
+		)
+	}
+
+	for _, env := range envs {
+		if err := exportEnvironment(r, env.key, env.value); err != nil {
+			return err
+		}
+		log.Donef("The %s is now available in the Environment Variable: %s (value: %s)", env.name, env.key, env.value)
+	}
+	return nil
+}
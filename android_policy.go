@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/android"
+)
+
+// Modes of the Android policy check, the check is turned off by default
+const (
+	policyCheckWarn = "warn"
+	policyCheckFail = "fail"
+)
+
+// androidPolicy describes the Play Store requirements the exported APKs and AABs are checked against
+type androidPolicy struct {
+	// minTargetSDK is not checked if 0
+	minTargetSDK int
+	// permissionAllowlist lists the dangerous permissions the app may request
+	permissionAllowlist []string
+	forbidDebuggable    bool
+}
+
+// newAndroidPolicy creates the policy from the step inputs, debuggable builds are only forbidden in the release configuration
+func newAndroidPolicy(configs config) androidPolicy {
+	policy := androidPolicy{
+		minTargetSDK:     configs.AndroidMinTargetSDK,
+		forbidDebuggable: configs.Configuration == "release",
+	}
+	for _, permission := range configs.AndroidPermissionAllowlist {
+		if permission = strings.TrimSpace(permission); permission != "" {
+			policy.permissionAllowlist = append(policy.permissionAllowlist, android.PermissionName(permission))
+		}
+	}
+	return policy
+}
+
+// violations returns the policy violations of the AndroidManifest.xml
+func (p androidPolicy) violations(androidManifest android.Manifest) []string {
+	var violations []string
+	if p.minTargetSDK > 0 && androidManifest.TargetSDK < p.minTargetSDK {
+		violations = append(violations, fmt.Sprintf("targetSdkVersion %d is below the required %d", androidManifest.TargetSDK, p.minTargetSDK))
+	}
+	if p.forbidDebuggable && androidManifest.Debuggable {
+		violations = append(violations, "android:debuggable is true in a release build")
+	}
+	for _, permission := range androidManifest.Permissions {
+		if !android.IsDangerousPermission(permission) {
+			continue
+		}
+		allowed := false
+		for _, allowedPermission := range p.permissionAllowlist {
+			if allowedPermission == permission {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, fmt.Sprintf("the dangerous permission %s is not allowed", permission))
+		}
+	}
+	return violations
+}
+
+// checkAndroidPolicy checks the exported APKs or AABs against the policy and reports the violations.
+// An artifact, which AndroidManifest.xml could not be read, violates the policy.
+func checkAndroidPolicy(policy androidPolicy, mode string, deployedPaths []string, androidManifests map[string]android.Manifest) []string {
+	report := log.Warnf
+	if mode == policyCheckFail {
+		report = log.Errorf
+	}
+
+	var violations []string
+	for _, pth := range deployedPaths {
+		var artifactViolations []string
+		if androidManifest, ok := androidManifests[pth]; ok {
+			artifactViolations = policy.violations(androidManifest)
+		} else {
+			artifactViolations = []string{"the AndroidManifest.xml could not be read"}
+		}
+
+		for _, violation := range artifactViolations {
+			violation = fmt.Sprintf("%s: %s", filepath.Base(pth), violation)
+			report("Android policy violation: %s", violation)
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) == 0 {
+		log.Donef("Android policy check passed")
+	}
+	return violations
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/android"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newAndroidPolicy(t *testing.T) {
+	policy := newAndroidPolicy(config{
+		Configuration:              "release",
+		AndroidMinTargetSDK:        33,
+		AndroidPermissionAllowlist: []string{"CAMERA", " android.permission.RECORD_AUDIO ", ""},
+	})
+	require.Equal(t, androidPolicy{
+		minTargetSDK:        33,
+		permissionAllowlist: []string{"android.permission.CAMERA", "android.permission.RECORD_AUDIO"},
+		forbidDebuggable:    true,
+	}, policy)
+
+	require.False(t, newAndroidPolicy(config{Configuration: "debug"}).forbidDebuggable)
+}
+
+func Test_androidPolicy_violations(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   androidPolicy
+		manifest android.Manifest
+		want     []string
+	}{
+		{
+			name:     "compliant",
+			policy:   androidPolicy{minTargetSDK: 33, permissionAllowlist: []string{"android.permission.CAMERA"}, forbidDebuggable: true},
+			manifest: android.Manifest{TargetSDK: 34, Permissions: []string{"android.permission.INTERNET", "android.permission.CAMERA"}},
+		},
+		{
+			name:     "target SDK below the minimum",
+			policy:   androidPolicy{minTargetSDK: 34},
+			manifest: android.Manifest{TargetSDK: 33},
+			want:     []string{"targetSdkVersion 33 is below the required 34"},
+		},
+		{
+			name:     "debuggable release",
+			policy:   androidPolicy{forbidDebuggable: true},
+			manifest: android.Manifest{TargetSDK: 33, Debuggable: true},
+			want:     []string{"android:debuggable is true in a release build"},
+		},
+		{
+			name:     "debuggable debug",
+			policy:   androidPolicy{},
+			manifest: android.Manifest{TargetSDK: 33, Debuggable: true},
+		},
+		{
+			name:     "dangerous permission not in the allowlist",
+			policy:   androidPolicy{permissionAllowlist: []string{"android.permission.CAMERA"}},
+			manifest: android.Manifest{Permissions: []string{"android.permission.CAMERA", "android.permission.READ_SMS", "com.example.permission.C2D"}},
+			want:     []string{"the dangerous permission android.permission.READ_SMS is not allowed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.policy.violations(tt.manifest))
+		})
+	}
+}
+
+func Test_checkAndroidPolicy(t *testing.T) {
+	policy := androidPolicy{minTargetSDK: 34}
+	manifests := map[string]android.Manifest{"/deploy/app-release.apk": {TargetSDK: 33}}
+
+	got := checkAndroidPolicy(policy, policyCheckFail, []string{"/deploy/app-release.apk", "/deploy/app-free-release.apk"}, manifests)
+	require.Equal(t, []string{
+		"app-release.apk: targetSdkVersion 33 is below the required 34",
+		"app-free-release.apk: the AndroidManifest.xml could not be read",
+	}, got)
+}
@@ -0,0 +1,189 @@
+// Package breakdown splits the size of the app archives (ipa, apk, aab) by the category of their entries
+package breakdown
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Categories of the archive entries, the native libraries are categorized per ABI (NativeLibs + " (<abi>)")
+const (
+	Dex        = "dex"
+	NativeLibs = "native libs"
+	WebAssets  = "assets/www"
+	Assets     = "assets"
+	Resources  = "resources"
+	Frameworks = "frameworks"
+	Other      = "other"
+)
+
+// Category is the total size of the entries of a category
+type Category struct {
+	Name string `json:"name"`
+	// Size is the compressed size, the entries take up in the archive
+	Size             int64 `json:"size"`
+	UncompressedSize int64 `json:"uncompressed_size"`
+	Files            int   `json:"files"`
+}
+
+// Report is the size breakdown of an archive
+type Report struct {
+	// Size is the size of the archive file
+	Size int64 `json:"size"`
+	// Categories are ordered by size, the largest first
+	Categories []Category `json:"categories"`
+	// NativeLibs are the native libraries (<abi>/<file> of an apk or aab) or the frameworks (<name>.framework of an ipa), sorted
+	NativeLibs []string `json:"native_libs,omitempty"`
+	// WebAssets are the files of the web app relative to its root (assets/www, assets/public, www or public), sorted
+	WebAssets []string `json:"web_assets,omitempty"`
+}
+
+// Archive reads the entries of the ipa, apk or aab and sums up their sizes by category
+func Archive(pth string) (Report, error) {
+	info, err := os.Stat(pth)
+	if err != nil {
+		return Report{}, err
+	}
+
+	r, err := zip.OpenReader(pth)
+	if err != nil {
+		return Report{}, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	categorize := apkCategory
+	switch filepath.Ext(pth) {
+	case ".ipa":
+		categorize = ipaCategory
+	case ".aab":
+		categorize = aabCategory
+	}
+
+	report := Report{Size: info.Size()}
+	categories := map[string]*Category{}
+	frameworks := map[string]bool{}
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, rel := categorize(f.Name)
+		switch {
+		case name == WebAssets:
+			report.WebAssets = append(report.WebAssets, rel)
+		case name == Frameworks:
+			frameworks[strings.SplitN(rel, "/", 2)[0]] = true
+		case strings.HasPrefix(name, NativeLibs):
+			report.NativeLibs = append(report.NativeLibs, rel)
+		}
+
+		category, ok := categories[name]
+		if !ok {
+			category = &Category{Name: name}
+			categories[name] = category
+		}
+		category.Size += int64(f.CompressedSize64)
+		category.UncompressedSize += int64(f.UncompressedSize64)
+		category.Files++
+	}
+
+	for framework := range frameworks {
+		report.NativeLibs = append(report.NativeLibs, framework)
+	}
+	sort.Strings(report.NativeLibs)
+	sort.Strings(report.WebAssets)
+
+	for _, category := range categories {
+		report.Categories = append(report.Categories, *category)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		if report.Categories[i].Size != report.Categories[j].Size {
+			return report.Categories[i].Size > report.Categories[j].Size
+		}
+		return report.Categories[i].Name < report.Categories[j].Name
+	})
+	return report, nil
+}
+
+// apkCategory categorizes the entries of an APK:
+// classes*.dex, lib/<abi>/, assets/www/ (Cordova) or assets/public/ (Capacitor), assets/, res/ and resources.arsc.
+// It also returns the path of the native libraries and the web assets relative to lib/ and the web root.
+func apkCategory(name string) (string, string) {
+	switch {
+	case strings.HasSuffix(name, ".dex") && !strings.Contains(name, "/"):
+		return Dex, name
+	case strings.HasPrefix(name, "lib/"):
+		return nativeLibsCategory(strings.TrimPrefix(name, "lib/"))
+	case strings.HasPrefix(name, "assets/www/"):
+		return WebAssets, strings.TrimPrefix(name, "assets/www/")
+	case strings.HasPrefix(name, "assets/public/"):
+		return WebAssets, strings.TrimPrefix(name, "assets/public/")
+	case strings.HasPrefix(name, "assets/"):
+		return Assets, name
+	case strings.HasPrefix(name, "res/"), name == "resources.arsc":
+		return Resources, name
+	}
+	return Other, name
+}
+
+// aabCategory categorizes the entries of an AAB, the modules (base/, feature modules) have the same layout:
+// dex/, lib/<abi>/, assets/www/ or assets/public/, assets/, res/ and resources.pb.
+func aabCategory(name string) (string, string) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) < 2 || parts[0] == "BUNDLE-METADATA" || parts[0] == "META-INF" {
+		return Other, name
+	}
+	module, name := parts[0], parts[1]
+
+	switch {
+	case strings.HasPrefix(name, "dex/"):
+		return Dex, name
+	case strings.HasPrefix(name, "lib/"):
+		return nativeLibsCategory(strings.TrimPrefix(name, "lib/"))
+	case strings.HasPrefix(name, "assets/www/"):
+		return WebAssets, strings.TrimPrefix(name, "assets/www/")
+	case strings.HasPrefix(name, "assets/public/"):
+		return WebAssets, strings.TrimPrefix(name, "assets/public/")
+	case strings.HasPrefix(name, "assets/"):
+		return Assets, name
+	case strings.HasPrefix(name, "res/"), name == "resources.pb":
+		return Resources, name
+	}
+	return Other, module + "/" + name
+}
+
+// ipaCategory categorizes the entries of an IPA, the paths are relative to Payload/<name>.app/:
+// Frameworks/, www/ (Cordova) or public/ (Capacitor) and the compiled resources (Assets.car, storyboards, nibs, localizations).
+func ipaCategory(name string) (string, string) {
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) < 3 || parts[0] != "Payload" {
+		return Other, name
+	}
+	name = parts[2]
+
+	switch {
+	case strings.HasPrefix(name, "Frameworks/"):
+		return Frameworks, strings.TrimPrefix(name, "Frameworks/")
+	case strings.HasPrefix(name, "www/"):
+		return WebAssets, strings.TrimPrefix(name, "www/")
+	case strings.HasPrefix(name, "public/"):
+		return WebAssets, strings.TrimPrefix(name, "public/")
+	}
+
+	first := strings.SplitN(name, "/", 2)[0]
+	switch filepath.Ext(first) {
+	case ".car", ".nib", ".storyboardc", ".lproj", ".png", ".bundle":
+		return Resources, name
+	}
+	return Other, name
+}
+
+// nativeLibsCategory returns the category of the ABI and the <abi>/<file> path of the native library
+func nativeLibsCategory(libPath string) (string, string) {
+	abi := strings.SplitN(libPath, "/", 2)[0]
+	return NativeLibs + " (" + abi + ")", libPath
+}
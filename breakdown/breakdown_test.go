@@ -0,0 +1,134 @@
+package breakdown
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeArchive writes the entries uncompressed, so that their compressed size is their length
+func writeArchive(t *testing.T, pth string, entries map[string]int) {
+	f, err := os.Create(pth)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for name, size := range entries {
+		entryWriter, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		require.NoError(t, err)
+		_, err = entryWriter.Write([]byte(strings.Repeat("x", size)))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func Test_Archive(t *testing.T) {
+	tests := []struct {
+		name           string
+		file           string
+		entries        map[string]int
+		want           []Category
+		wantNativeLibs []string
+		wantWebAssets  []string
+	}{
+		{
+			name: "apk",
+			file: "app.apk",
+			entries: map[string]int{
+				"classes.dex":                     400,
+				"classes2.dex":                    100,
+				"lib/arm64-v8a/libsqlite.so":      300,
+				"lib/armeabi-v7a/libsqlite.so":    200,
+				"assets/www/index.html":           50,
+				"assets/www/main.js":              250,
+				"assets/capacitor.config.json":    10,
+				"res/drawable/splash.png":         60,
+				"resources.arsc":                  40,
+				"AndroidManifest.xml":             5,
+				"META-INF/MANIFEST.MF":            5,
+				"META-INF/services/some/file.dex": 1,
+			},
+			want: []Category{
+				{Name: Dex, Size: 500, UncompressedSize: 500, Files: 2},
+				{Name: WebAssets, Size: 300, UncompressedSize: 300, Files: 2},
+				{Name: "native libs (arm64-v8a)", Size: 300, UncompressedSize: 300, Files: 1},
+				{Name: "native libs (armeabi-v7a)", Size: 200, UncompressedSize: 200, Files: 1},
+				{Name: Resources, Size: 100, UncompressedSize: 100, Files: 2},
+				{Name: Other, Size: 11, UncompressedSize: 11, Files: 3},
+				{Name: Assets, Size: 10, UncompressedSize: 10, Files: 1},
+			},
+			wantNativeLibs: []string{"arm64-v8a/libsqlite.so", "armeabi-v7a/libsqlite.so"},
+			wantWebAssets:  []string{"index.html", "main.js"},
+		},
+		{
+			name: "aab",
+			file: "app.aab",
+			entries: map[string]int{
+				"base/dex/classes.dex":               400,
+				"base/lib/x86_64/libsqlite.so":       300,
+				"base/assets/public/index.html":      200,
+				"base/res/drawable/splash.png":       60,
+				"base/resources.pb":                  40,
+				"base/manifest/AndroidManifest.xml":  5,
+				"BUNDLE-METADATA/com.android/x.json": 5,
+				"BundleConfig.pb":                    5,
+			},
+			want: []Category{
+				{Name: Dex, Size: 400, UncompressedSize: 400, Files: 1},
+				{Name: "native libs (x86_64)", Size: 300, UncompressedSize: 300, Files: 1},
+				{Name: WebAssets, Size: 200, UncompressedSize: 200, Files: 1},
+				{Name: Resources, Size: 100, UncompressedSize: 100, Files: 2},
+				{Name: Other, Size: 15, UncompressedSize: 15, Files: 3},
+			},
+			wantNativeLibs: []string{"x86_64/libsqlite.so"},
+			wantWebAssets:  []string{"index.html"},
+		},
+		{
+			name: "ipa",
+			file: "app.ipa",
+			entries: map[string]int{
+				"Payload/App.app/App": 500,
+				"Payload/App.app/Frameworks/Capacitor.framework/Capacitor":       400,
+				"Payload/App.app/Frameworks/Cordova.framework/Cordova":           100,
+				"Payload/App.app/public/index.html":                              300,
+				"Payload/App.app/Assets.car":                                     80,
+				"Payload/App.app/Base.lproj/LaunchScreen.storyboardc/Info.plist": 20,
+				"Payload/App.app/Info.plist":                                     10,
+			},
+			want: []Category{
+				{Name: Other, Size: 510, UncompressedSize: 510, Files: 2},
+				{Name: Frameworks, Size: 500, UncompressedSize: 500, Files: 2},
+				{Name: WebAssets, Size: 300, UncompressedSize: 300, Files: 1},
+				{Name: Resources, Size: 100, UncompressedSize: 100, Files: 2},
+			},
+			wantNativeLibs: []string{"Capacitor.framework", "Cordova.framework"},
+			wantWebAssets:  []string{"index.html"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), tt.file)
+			writeArchive(t, pth, tt.entries)
+			info, err := os.Stat(pth)
+			require.NoError(t, err)
+
+			got, err := Archive(pth)
+			require.NoError(t, err)
+			require.Equal(t, info.Size(), got.Size)
+			require.Equal(t, tt.want, got.Categories)
+			require.Equal(t, tt.wantNativeLibs, got.NativeLibs)
+			require.Equal(t, tt.wantWebAssets, got.WebAssets)
+		})
+	}
+}
+
+func Test_Archive_notZip(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "app.apk")
+	require.NoError(t, os.WriteFile(pth, []byte("apk"), 0600))
+
+	_, err := Archive(pth)
+	require.Error(t, err)
+}
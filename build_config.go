@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cordovaBuildConfig models the build.json (generated by the Generate cordova build configuration step),
+// the keys of the platform maps are the build configurations (debug, release).
+type cordovaBuildConfig struct {
+	Android map[string]androidBuildConfig `json:"android"`
+	IOS     map[string]iosBuildConfig     `json:"ios"`
+}
+
+type androidBuildConfig struct {
+	Keystore      string `json:"keystore"`
+	StorePassword string `json:"storePassword"`
+	Alias         string `json:"alias"`
+	Password      string `json:"password"`
+	KeystoreType  string `json:"keystoreType"`
+}
+
+type iosBuildConfig struct {
+	CodeSignIdentity    string `json:"codeSignIdentity"`
+	DevelopmentTeam     string `json:"developmentTeam"`
+	PackageType         string `json:"packageType"`
+	ProvisioningProfile string `json:"provisioningProfile"`
+}
+
+// readBuildConfig parses the build.json, an empty path results in an empty config
+func readBuildConfig(pth string) (cordovaBuildConfig, error) {
+	var buildConfig cordovaBuildConfig
+	if pth == "" {
+		return buildConfig, nil
+	}
+
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return buildConfig, fmt.Errorf("failed to read build config (%s), error: %s", pth, err)
+	}
+	if err := json.Unmarshal(content, &buildConfig); err != nil {
+		return buildConfig, fmt.Errorf("failed to parse build config (%s), error: %s", pth, err)
+	}
+	return buildConfig, nil
+}
+
+// androidSigning returns the android signing properties of the configuration, with the keystore path made absolute
+func (c cordovaBuildConfig) androidSigning(configuration, workDir string) androidBuildConfig {
+	signing := c.Android[configuration]
+	if signing.Keystore != "" && !filepath.IsAbs(signing.Keystore) {
+		signing.Keystore = filepath.Join(workDir, signing.Keystore)
+	}
+	return signing
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-ionic-archive/capacitor"
+	"github.com/bitrise-steplib/steps-ionic-archive/discovery"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const (
+	integrationCordova   = "cordova"
+	integrationCapacitor = "capacitor"
+)
+
+// buildCapacitor builds the web assets, syncs them into the native projects and builds the native projects.
+// The options groups are passed to `ionic build`, `npx cap sync` and to gradle/xcodebuild respectively.
+// The web assets are built once for every platform, so only the sync and the native groups of the platform options are used.
+// The platforms are synced and built concurrently if parallel is set, and every platform is built even if an other one fails
+// if configs.ContinueOnFailure is set (see runPlatformBuilds), the logs of the concurrent builds are created by createLog.
+// The web build and the platform builds are timed by the timer.
+func buildCapacitor(r runner.Runner, workDir string, configs config, platforms []string, parallel, isAAB bool, options []string, platformOptions map[string][]string, createLog func(name string) (*os.File, error), timer *phaseTimer) error {
+	groupArgs := splitOptionGroups(options)
+
+	buildConfig, err := readBuildConfig(configs.BuildConfig)
+	if err != nil {
+		return err
+	}
+
+	timer.enter(timingWebBuild)
+	webBuildCmd := capacitor.WebBuildCommand(configs.Configuration, groupArgs[0])
+	if err := runBuildCommand(r, webBuildCmd); err != nil {
+		return err
+	}
+
+	return runPlatformBuilds(r, platforms, parallel, configs.ContinueOnFailure, os.Stdout, createLog, timer, func(r runner.Runner, platform string) error {
+		platformGroupArgs := splitOptionGroups(platformOptions[platform])
+		if len(platformGroupArgs[0]) > 0 {
+			log.Warnf("The %s options of the web build (%s) are ignored, the web assets are built once for every platform", platform, strings.Join(platformGroupArgs[0], " "))
+		}
+		platformGroupArgs = mergeOptionGroups(groupArgs, platformGroupArgs)
+
+		syncCmd := capacitor.SyncCommand(platform, platformGroupArgs[1])
+		if err := runBuildCommand(r, syncCmd); err != nil {
+			return err
+		}
+
+		switch platform {
+		case "android":
+			if err := buildCapacitorAndroid(r, workDir, configs.Configuration, isAAB, buildConfig, platformGroupArgs[2]); err != nil {
+				return err
+			}
+		case "ios":
+			if err := buildCapacitorIOS(r, workDir, configs.Configuration, configs.Target, buildConfig, platformGroupArgs[2]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported platform: %s", platform)
+		}
+		return nil
+	})
+}
+
+func buildCapacitorAndroid(r runner.Runner, workDir, configuration string, isAAB bool, buildConfig cordovaBuildConfig, options []string) error {
+	signing := buildConfig.androidSigning(configuration, workDir)
+
+	var gradleArgs []string
+	gradleArgs = append(gradleArgs, capacitor.GradleSigningProperties(signing.Keystore, signing.StorePassword, signing.Alias, signing.Password)...)
+	gradleArgs = append(gradleArgs, options...)
+
+	cmd := capacitor.GradleCommand(capacitor.AndroidProjectDir(workDir), capacitor.GradleTask(configuration, isAAB), gradleArgs)
+	return runBuildCommand(r, cmd)
+}
+
+func buildCapacitorIOS(r runner.Runner, workDir, configuration, target string, buildConfig cordovaBuildConfig, options []string) error {
+	projectDir := capacitor.IOSProjectDir(workDir)
+	hasWorkspace, err := pathutil.IsDirExists(filepath.Join(projectDir, "App.xcworkspace"))
+	if err != nil {
+		return err
+	}
+	projectArgs := capacitor.XcodeProjectArgs(projectDir, hasWorkspace)
+
+	buildDir := filepath.Join(workDir, "ios", "build")
+	derivedDataPath := filepath.Join(buildDir, "DerivedData")
+
+	if target != "device" {
+		return runBuildCommand(r, capacitor.SimulatorBuildCommand(projectArgs, configuration, derivedDataPath, options))
+	}
+
+	iosConfig := buildConfig.IOS[configuration]
+	signing := capacitor.IOSSigning{
+		CodeSignIdentity:    iosConfig.CodeSignIdentity,
+		DevelopmentTeam:     iosConfig.DevelopmentTeam,
+		ProvisioningProfile: iosConfig.ProvisioningProfile,
+		ExportMethod:        iosConfig.PackageType,
+	}
+	if capacitorConfig, err := capacitor.ReadConfig(workDir); err != nil {
+		log.Warnf("Failed to read Capacitor config, error: %s", err)
+	} else {
+		signing.BundleID = capacitorConfig.AppID
+	}
+
+	outputDir := capacitor.IOSOutputDir(workDir, target, configuration)
+	archivePath := filepath.Join(outputDir, capacitor.Scheme+".xcarchive")
+	if err := runBuildCommand(r, capacitor.ArchiveCommand(projectArgs, configuration, derivedDataPath, archivePath, signing, options)); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(buildDir, 0755); err != nil {
+		return err
+	}
+	exportOptionsPath := filepath.Join(buildDir, "ExportOptions.plist")
+	if err := os.WriteFile(exportOptionsPath, signing.ExportOptions(), 0600); err != nil {
+		return fmt.Errorf("failed to write export options (%s), error: %s", exportOptionsPath, err)
+	}
+
+	return runBuildCommand(r, capacitor.ExportArchiveCommand(archivePath, outputDir, exportOptionsPath))
+}
+
+func runBuildCommand(r runner.Runner, cmd *runner.Command) error {
+	cmd.SetStdout(os.Stdout).SetStderr(os.Stderr)
+
+	fmt.Println()
+	log.Donef("$ %s", redactedCommandArgs(cmd))
+
+	if err := r.Run(cmd); err != nil {
+		return fmt.Errorf("command failed, error: %w", err)
+	}
+	return nil
+}
+
+// redactedCommandArgs returns the printable command, without the gradle signing passwords
+func redactedCommandArgs(cmd *runner.Command) string {
+	args := cmd.Slice()
+	printable := make([]string, len(args))
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-Pandroid.injected.signing.") && strings.Contains(arg, "password=") {
+			arg = arg[:strings.Index(arg, "=")+1] + "***"
+		}
+		printable[i] = arg
+	}
+	return command.PrintableCommandArgs(false, printable)
+}
+
+// capacitorOutputRules returns where the native builds of the platforms write the outputs
+func capacitorOutputRules(workDir string, platforms []string, target, configuration string, isAAB bool) []discovery.Rule {
+	var rules []discovery.Rule
+	for _, platform := range platforms {
+		switch platform {
+		case "ios":
+			rules = append(rules, iosOutputRules(capacitor.IOSOutputDir(workDir, target, configuration))...)
+		case "android":
+			dir, ext := filepath.Join(capacitor.AndroidProjectDir(workDir), "app", "build", "outputs", "apk"), "apk"
+			if isAAB {
+				dir, ext = filepath.Join(capacitor.AndroidProjectDir(workDir), "app", "build", "outputs", "bundle"), "aab"
+			}
+			rules = append(rules, discovery.Rule{Dir: dir, Ext: ext})
+		}
+	}
+	return rules
+}
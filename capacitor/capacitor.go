@@ -0,0 +1,206 @@
+package capacitor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+// Scheme is the Xcode scheme of the native iOS project generated by Capacitor
+const Scheme = "App"
+
+// WebBuildCommand returns the ionic build command model, which builds the web assets into the web dir
+func WebBuildCommand(configuration string, options []string) *runner.Command {
+	cmdArgs := []string{"ionic", "build"}
+	if configuration == "release" {
+		cmdArgs = append(cmdArgs, "--prod")
+	}
+	cmdArgs = append(cmdArgs, options...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...)
+}
+
+// SyncCommand returns npx cap sync command model, which copies the web assets and updates the native project
+func SyncCommand(platform string, options []string) *runner.Command {
+	cmdArgs := []string{"npx", "cap", "sync", platform}
+	cmdArgs = append(cmdArgs, options...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...)
+}
+
+// AndroidProjectDir returns the native Android project's directory
+func AndroidProjectDir(workDir string) string {
+	return filepath.Join(workDir, "android")
+}
+
+// IOSProjectDir returns the native iOS project's directory
+func IOSProjectDir(workDir string) string {
+	return filepath.Join(workDir, "ios", "App")
+}
+
+// GradleTask returns the gradle task, which builds an apk or aab for the given configuration
+func GradleTask(configuration string, isAAB bool) string {
+	task := "assemble"
+	if isAAB {
+		task = "bundle"
+	}
+	return task + strings.Title(configuration) //nolint:staticcheck
+}
+
+// GradleSigningProperties returns the gradle project properties, which inject the release signing config
+func GradleSigningProperties(keystore, storePassword, alias, keyPassword string) []string {
+	if keystore == "" {
+		return nil
+	}
+	return []string{
+		"-Pandroid.injected.signing.store.file=" + keystore,
+		"-Pandroid.injected.signing.store.password=" + storePassword,
+		"-Pandroid.injected.signing.key.alias=" + alias,
+		"-Pandroid.injected.signing.key.password=" + keyPassword,
+	}
+}
+
+// GradleCommand returns the gradle wrapper command model of the native Android project
+func GradleCommand(projectDir string, task string, options []string) *runner.Command {
+	cmdArgs := []string{"./gradlew", task}
+	cmdArgs = append(cmdArgs, options...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...).SetDir(projectDir)
+}
+
+// XcodeProjectArgs returns the xcodebuild arguments selecting the workspace (CocoaPods) or the project (Swift Package Manager)
+func XcodeProjectArgs(projectDir string, hasWorkspace bool) []string {
+	if hasWorkspace {
+		return []string{"-workspace", filepath.Join(projectDir, "App.xcworkspace")}
+	}
+	return []string{"-project", filepath.Join(projectDir, "App.xcodeproj")}
+}
+
+// SDK returns the xcodebuild sdk belonging to the build target
+func SDK(target string) string {
+	if target == "device" {
+		return "iphoneos"
+	}
+	return "iphonesimulator"
+}
+
+// IOSOutputDir returns the directory, where the iOS build outputs (.app, .dSYM, .ipa) are placed
+func IOSOutputDir(workDir, target, configuration string) string {
+	buildDir := filepath.Join(workDir, "ios", "build")
+	configurationComponent := strings.Title(configuration) + "-" + SDK(target) //nolint:staticcheck
+	if target == "device" {
+		return filepath.Join(buildDir, configurationComponent)
+	}
+	return filepath.Join(buildDir, "DerivedData", "Build", "Products", configurationComponent)
+}
+
+// SimulatorBuildCommand returns the xcodebuild command model, which builds an unsigned simulator .app
+func SimulatorBuildCommand(projectArgs []string, configuration, derivedDataPath string, options []string) *runner.Command {
+	cmdArgs := []string{"xcodebuild", "build"}
+	cmdArgs = append(cmdArgs, projectArgs...)
+	cmdArgs = append(cmdArgs,
+		"-scheme", Scheme,
+		"-configuration", strings.Title(configuration), //nolint:staticcheck
+		"-sdk", SDK("emulator"),
+		"-derivedDataPath", derivedDataPath,
+		"CODE_SIGNING_ALLOWED=NO",
+	)
+	cmdArgs = append(cmdArgs, options...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...)
+}
+
+// ArchiveCommand returns the xcodebuild archive command model
+func ArchiveCommand(projectArgs []string, configuration, derivedDataPath, archivePath string, signing IOSSigning, options []string) *runner.Command {
+	cmdArgs := []string{"xcodebuild", "archive"}
+	cmdArgs = append(cmdArgs, projectArgs...)
+	cmdArgs = append(cmdArgs,
+		"-scheme", Scheme,
+		"-configuration", strings.Title(configuration), //nolint:staticcheck
+		"-sdk", SDK("device"),
+		"-derivedDataPath", derivedDataPath,
+		"-archivePath", archivePath,
+	)
+	cmdArgs = append(cmdArgs, signing.buildSettings()...)
+	cmdArgs = append(cmdArgs, options...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...)
+}
+
+// ExportArchiveCommand returns the xcodebuild -exportArchive command model
+func ExportArchiveCommand(archivePath, exportPath, exportOptionsPath string) *runner.Command {
+	return runner.New("xcodebuild", "-exportArchive",
+		"-archivePath", archivePath,
+		"-exportPath", exportPath,
+		"-exportOptionsPlist", exportOptionsPath,
+	)
+}
+
+// IOSSigning describes the iOS code signing properties (the ios section of the cordova build.json)
+type IOSSigning struct {
+	CodeSignIdentity    string
+	DevelopmentTeam     string
+	ProvisioningProfile string
+	ExportMethod        string
+	BundleID            string
+}
+
+func (s IOSSigning) buildSettings() []string {
+	var settings []string
+	if s.DevelopmentTeam != "" {
+		settings = append(settings, "DEVELOPMENT_TEAM="+s.DevelopmentTeam)
+	}
+	if s.CodeSignIdentity != "" {
+		settings = append(settings, "CODE_SIGN_IDENTITY="+s.CodeSignIdentity)
+	}
+	if s.ProvisioningProfile != "" {
+		settings = append(settings, "CODE_SIGN_STYLE=Manual", "PROVISIONING_PROFILE="+s.ProvisioningProfile)
+	}
+	return settings
+}
+
+// ExportOptions returns the content of the export options plist used by xcodebuild -exportArchive
+func (s IOSSigning) ExportOptions() []byte {
+	method := s.ExportMethod
+	if method == "" {
+		method = "development"
+	}
+
+	entries := map[string]string{"method": method}
+	if s.DevelopmentTeam != "" {
+		entries["teamID"] = s.DevelopmentTeam
+	}
+
+	var profiles string
+	if s.ProvisioningProfile != "" && s.BundleID != "" {
+		entries["signingStyle"] = "manual"
+		profiles = fmt.Sprintf("\t<key>provisioningProfiles</key>\n\t<dict>\n\t\t<key>%s</key>\n\t\t<string>%s</string>\n\t</dict>\n", xmlEscape(s.BundleID), xmlEscape(s.ProvisioningProfile))
+	} else {
+		entries["signingStyle"] = "automatic"
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString(`<plist version="1.0">` + "\n<dict>\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<string>%s</string>\n", key, xmlEscape(entries[key]))
+	}
+	b.WriteString(profiles)
+	b.WriteString("</dict>\n</plist>\n")
+	return b.Bytes()
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	if err := xml.EscapeText(&b, []byte(s)); err != nil {
+		return s
+	}
+	return b.String()
+}
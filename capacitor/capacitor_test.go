@@ -0,0 +1,83 @@
+package capacitor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GradleTask(t *testing.T) {
+	tests := []struct {
+		name          string
+		configuration string
+		isAAB         bool
+		want          string
+	}{
+		{name: "release apk", configuration: "release", isAAB: false, want: "assembleRelease"},
+		{name: "release aab", configuration: "release", isAAB: true, want: "bundleRelease"},
+		{name: "debug apk", configuration: "debug", isAAB: false, want: "assembleDebug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, GradleTask(tt.configuration, tt.isAAB))
+		})
+	}
+}
+
+func Test_IOSOutputDir(t *testing.T) {
+	require.Equal(t, "/workdir/ios/build/Release-iphoneos", IOSOutputDir("/workdir", "device", "release"))
+	require.Equal(t, "/workdir/ios/build/DerivedData/Build/Products/Debug-iphonesimulator", IOSOutputDir("/workdir", "emulator", "debug"))
+}
+
+func Test_ArchiveCommand(t *testing.T) {
+	signing := IOSSigning{DevelopmentTeam: "ABCD1234", ProvisioningProfile: "profile-uuid"}
+	cmd := ArchiveCommand(XcodeProjectArgs("/workdir/ios/App", true), "release", "/dd", "/out/App.xcarchive", signing, nil)
+	require.Equal(t, []string{
+		"xcodebuild", "archive",
+		"-workspace", "/workdir/ios/App/App.xcworkspace",
+		"-scheme", "App",
+		"-configuration", "Release",
+		"-sdk", "iphoneos",
+		"-derivedDataPath", "/dd",
+		"-archivePath", "/out/App.xcarchive",
+		"DEVELOPMENT_TEAM=ABCD1234",
+		"CODE_SIGN_STYLE=Manual", "PROVISIONING_PROFILE=profile-uuid",
+	}, cmd.Slice())
+}
+
+func Test_ExportOptions(t *testing.T) {
+	signing := IOSSigning{DevelopmentTeam: "ABCD1234", ProvisioningProfile: "profile-uuid", ExportMethod: "app-store", BundleID: "io.ionic.starter"}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>method</key>
+	<string>app-store</string>
+	<key>signingStyle</key>
+	<string>manual</string>
+	<key>teamID</key>
+	<string>ABCD1234</string>
+	<key>provisioningProfiles</key>
+	<dict>
+		<key>io.ionic.starter</key>
+		<string>profile-uuid</string>
+	</dict>
+</dict>
+</plist>
+`
+	require.Equal(t, want, string(signing.ExportOptions()))
+}
+
+func Test_parseScriptConfig(t *testing.T) {
+	content := `import { CapacitorConfig } from '@capacitor/cli';
+
+const config: CapacitorConfig = {
+  appId: 'io.ionic.starter',
+  appName: "My App",
+  webDir: 'www',
+};
+
+export default config;
+`
+	require.Equal(t, Config{AppID: "io.ionic.starter", AppName: "My App", WebDir: "www"}, parseScriptConfig(content))
+}
@@ -0,0 +1,59 @@
+package capacitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Config is the subset of the Capacitor config (capacitor.config.json or capacitor.config.ts) used by the step
+type Config struct {
+	AppID   string `json:"appId"`
+	AppName string `json:"appName"`
+	WebDir  string `json:"webDir"`
+}
+
+// ConfigFileNames are the Capacitor config files in order of precedence
+var ConfigFileNames = []string{"capacitor.config.ts", "capacitor.config.js", "capacitor.config.json"}
+
+// ReadConfig reads the Capacitor config from the project dir
+func ReadConfig(projectDir string) (Config, error) {
+	for _, name := range ConfigFileNames {
+		pth := filepath.Join(projectDir, name)
+		content, err := os.ReadFile(pth)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return Config{}, err
+		}
+
+		if filepath.Ext(name) == ".json" {
+			var config Config
+			if err := json.Unmarshal(content, &config); err != nil {
+				return Config{}, fmt.Errorf("failed to parse %s: %s", pth, err)
+			}
+			return config, nil
+		}
+		return parseScriptConfig(string(content)), nil
+	}
+	return Config{}, fmt.Errorf("no Capacitor config found in %s", projectDir)
+}
+
+// parseScriptConfig picks the string literal properties from a capacitor.config.ts/js,
+// evaluating the script would require node, and the properties are literals in the generated configs.
+func parseScriptConfig(content string) Config {
+	property := func(name string) string {
+		re := regexp.MustCompile(name + `\s*:\s*['"` + "`" + `]([^'"` + "`" + `]+)['"` + "`" + `]`)
+		if match := re.FindStringSubmatch(content); len(match) == 2 {
+			return match[1]
+		}
+		return ""
+	}
+	return Config{
+		AppID:   property("appId"),
+		AppName: property("appName"),
+		WebDir:  property("webDir"),
+	}
+}
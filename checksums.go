@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	checksumsPathEnvKey = "BITRISE_IONIC_CHECKSUMS_PATH"
+	checksumsFileName   = "SHA256SUMS"
+	checksumSidecarExt  = ".sha256"
+)
+
+// checksumLine formats the digest of the file the same way as sha256sum does, so the files can be verified with `sha256sum -c`
+func checksumLine(sum, pth string) string {
+	return fmt.Sprintf("%s  %s\n", sum, filepath.Base(pth))
+}
+
+// writeChecksums writes a <file>.sha256 sidecar next to every exported file and zipped directory of the manifest,
+// and a combined SHA256SUMS file into the dir, it returns the path of the SHA256SUMS file.
+func writeChecksums(manifest artifactManifest, dir string) (string, error) {
+	var lines []string
+	for _, file := range manifest.files() {
+		line := checksumLine(file.sha256, file.path)
+		if err := os.WriteFile(file.path+checksumSidecarExt, []byte(line), 0644); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+
+	pth := filepath.Join(dir, checksumsFileName)
+	if err := os.WriteFile(pth, []byte(strings.Join(lines, "")), 0644); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
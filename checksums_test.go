@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_writeChecksums(t *testing.T) {
+	deployDir := t.TempDir()
+	manifest := artifactManifest{Artifacts: []manifestArtifact{
+		{Type: "ipa", DeployedPath: filepath.Join(deployDir, "app.ipa"), SHA256: "1111"},
+		{
+			Type:         "dSYM",
+			DeployedPath: filepath.Join(deployDir, "app.dSYM"),
+			SHA256:       "2222",
+			Zip:          &manifestZip{Path: filepath.Join(deployDir, "app.dSYM.zip"), SHA256: "3333"},
+		},
+		{Type: "app", DeployedPath: filepath.Join(deployDir, "App.app"), SHA256: "4444"},
+		{Type: "apk", DeployedPath: filepath.Join(deployDir, "app-release.apk"), SHA256: "5555"},
+	}}
+
+	pth, err := writeChecksums(manifest, deployDir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(deployDir, "SHA256SUMS"), pth)
+
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+	require.Equal(t, "1111  app.ipa\n3333  app.dSYM.zip\n5555  app-release.apk\n", string(content))
+
+	for name, want := range map[string]string{
+		"app.ipa.sha256":         "1111  app.ipa\n",
+		"app.dSYM.zip.sha256":    "3333  app.dSYM.zip\n",
+		"app-release.apk.sha256": "5555  app-release.apk\n",
+	} {
+		content, err := os.ReadFile(filepath.Join(deployDir, name))
+		require.NoError(t, err)
+		require.Equal(t, want, string(content))
+	}
+	_, err = os.Stat(filepath.Join(deployDir, "App.app.sha256"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+// copyFile copies the file's content and permissions, an existing dst (like the artifact of a previous run) is replaced
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("source is a directory: %s", src)
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Warnf("Failed to close %s: %s", src, err)
+		}
+	}()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyDir copies the directory to dst, symlinks (for example in the framework bundles of an .app) are preserved.
+// An existing dst is replaced, so no file of a previous run is left in it.
+func copyDir(src, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return filepath.Walk(src, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, pth)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(pth)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		default:
+			return copyFile(pth, target)
+		}
+	})
+}
+
+// zipDir zips the directory (as the zip's root entry) into dst, the zip tool is used to preserve symlinks.
+// An existing dst is replaced, as the zip tool would update it and keep its entries.
+func zipDir(r runner.Runner, src, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	cmd := runner.New("/usr/bin/zip", "-rTy", dst, filepath.Base(src)).SetDir(filepath.Dir(src))
+	if out, err := r.RunAndReturnTrimmedCombinedOutput(cmd); err != nil {
+		return fmt.Errorf("command: (%s) failed, output: %s, error: %s", cmd.PrintableCommandArgs(), out, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_copyDir(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "App.app")
+	writeFiles(t, src, map[string]string{
+		"App": "binary",
+		"Frameworks/Lib.framework/Versions/A/Lib":  "lib",
+		"Frameworks/Lib.framework/Versions/A/Info": "info",
+		"www/index.html": "<html></html>",
+	})
+	require.NoError(t, os.Symlink("A", filepath.Join(src, "Frameworks/Lib.framework/Versions/Current")))
+
+	dst := filepath.Join(t.TempDir(), "App.app")
+	require.NoError(t, copyDir(src, dst))
+
+	content, err := os.ReadFile(filepath.Join(dst, "www/index.html"))
+	require.NoError(t, err)
+	require.Equal(t, "<html></html>", string(content))
+
+	link, err := os.Readlink(filepath.Join(dst, "Frameworks/Lib.framework/Versions/Current"))
+	require.NoError(t, err)
+	require.Equal(t, "A", link)
+}
+
+func Test_copyDir_existingTarget(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "App.app")
+	writeFiles(t, src, map[string]string{"App": "new binary"})
+	require.NoError(t, os.Symlink("App", filepath.Join(src, "Current")))
+
+	// the artifact of a previous run in the deploy dir
+	dst := filepath.Join(t.TempDir(), "App.app")
+	writeFiles(t, dst, map[string]string{"App": "old binary", "Removed": "stale"})
+	require.NoError(t, os.Symlink("Removed", filepath.Join(dst, "Current")))
+
+	require.NoError(t, copyDir(src, dst))
+
+	content, err := os.ReadFile(filepath.Join(dst, "App"))
+	require.NoError(t, err)
+	require.Equal(t, "new binary", string(content))
+	link, err := os.Readlink(filepath.Join(dst, "Current"))
+	require.NoError(t, err)
+	require.Equal(t, "App", link)
+	require.NoFileExists(t, filepath.Join(dst, "Removed"))
+}
+
+func Test_copyFile_existingTarget(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "app-release.apk")
+	require.NoError(t, os.WriteFile(src, []byte("new apk"), 0644))
+	dst := filepath.Join(t.TempDir(), "app-release.apk")
+	require.NoError(t, os.WriteFile(dst, []byte("old apk"), 0444))
+
+	require.NoError(t, copyFile(src, dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, "new apk", string(content))
+}
+
+func Test_zipDir_existingTarget(t *testing.T) {
+	if _, err := os.Stat("/usr/bin/zip"); err != nil {
+		t.Skip("zipping requires /usr/bin/zip")
+	}
+
+	src := filepath.Join(t.TempDir(), "App.app")
+	writeFiles(t, src, map[string]string{"Removed": "stale"})
+	dst := filepath.Join(t.TempDir(), "App.app.zip")
+	require.NoError(t, zipDir(runner.NewDefault(), src, dst))
+
+	require.NoError(t, os.RemoveAll(src))
+	writeFiles(t, src, map[string]string{"App": "binary"})
+	require.NoError(t, zipDir(runner.NewDefault(), src, dst))
+
+	reader, err := zip.OpenReader(dst)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, reader.Close())
+	}()
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	require.Equal(t, []string{"App.app/", "App.app/App"}, names)
+}
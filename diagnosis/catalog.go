@@ -0,0 +1,100 @@
+package diagnosis
+
+import "regexp"
+
+// The failure categories, exported in the BITRISE_IONIC_FAILURE_CATEGORY output
+const (
+	CategoryAndroidSDKPlatform     = "android-sdk-platform"
+	CategoryAndroidBuildTools      = "android-build-tools"
+	CategoryJDKMismatch            = "jdk-mismatch"
+	CategoryGradleOutOfMemory      = "gradle-out-of-memory"
+	CategoryCodeSigningIdentity    = "ios-code-signing-identity"
+	CategoryProvisioningProfile    = "ios-provisioning-profile"
+	CategoryCocoaPods              = "cocoapods"
+	CategoryESMRequire             = "node-esm-require"
+	CategoryNodeVersion            = "node-version"
+	CategoryMissingNodeModule      = "node-module-missing"
+	CategoryNotCordovaProject      = "not-cordova-project"
+	CategoryNotIonicProject        = "not-ionic-project"
+	CategoryIonicAuthentication    = "ionic-authentication"
+	CategoryCordovaPlatformMissing = "cordova-platform-missing"
+	// CategoryUnknown is the category of the failed commands, which output doesn't match any signature
+	CategoryUnknown = "unknown"
+)
+
+// Catalog is the knowledge base of the known Gradle, xcodebuild, CocoaPods, cordova, ionic and Node.js failures.
+// The signatures are matched in order, so the more specific ones come first.
+// To add a known failure, append a signature with a pattern matching a line of the failed command's output
+// and a hint, which tells how to fix it.
+var Catalog = []Signature{
+	{
+		Category: CategoryAndroidSDKPlatform,
+		Pattern:  regexp.MustCompile(`(?i)failed to find target with hash string '?android-\d+|failed to find platform sdk with path: platforms;android-\d+|failed to find target android-\d+`),
+		Hint:     `The Android SDK platform, which the project compiles against, is not installed. Install it with sdkmanager "platforms;android-<API level>" (or with the Install missing Android SDK components step), or use a stack, which has it.`,
+	},
+	{
+		Category: CategoryAndroidBuildTools,
+		Pattern:  regexp.MustCompile(`(?i)failed to find build tools revision|no installed build tools found`),
+		Hint:     `The Android SDK Build Tools, which the project requires, are not installed. Install them with sdkmanager "build-tools;<version>", or set a preinstalled version in the project.`,
+	},
+	{
+		Category: CategoryJDKMismatch,
+		Pattern:  regexp.MustCompile(`(?i)unsupported class file major version \d+|android gradle plugin requires java \d+ to run|could not determine java version from|invalid source release: \d+|incompatible because this component declares a component compatible with java \d+`),
+		Hint:     `The active JDK doesn't match the Gradle and Android Gradle plugin versions of the project. Switch to the required Java version (for example with the Set Java version step) before the build.`,
+	},
+	{
+		Category: CategoryGradleOutOfMemory,
+		Pattern:  regexp.MustCompile(`java\.lang\.OutOfMemoryError|(?i)expiring daemon because jvm heap space is exhausted`),
+		Hint:     `Gradle ran out of memory. Increase the heap size with org.gradle.jvmargs (like -Xmx4g) in gradle.properties, for example with the cordova-android GradlePluginGradleProperties preference.`,
+	},
+	{
+		Category: CategoryCodeSigningIdentity,
+		Pattern:  regexp.MustCompile(`No signing certificate "[^"]*" found|No certificate for team '[^']*' matching '[^']*' found|(?i)code signing identity not found|no valid signing identities|errSecInternalComponent`),
+		Hint:     `The code signing certificate is not installed in the keychain. Install the certificate (for example with the Certificate and profile installer step) and check the codeSignIdentity and developmentTeam of the build configuration (build.json).`,
+	},
+	{
+		Category: CategoryProvisioningProfile,
+		Pattern:  regexp.MustCompile(`No profiles for '[^']*' were found|No provisioning profiles? (matching|with)|requires a provisioning profile|Provisioning profile "[^"]*" (doesn't|does not) (include|match)`),
+		Hint:     `No matching provisioning profile is installed. Install a profile of the app's bundle id and the export method, and check the provisioningProfile and packageType of the build configuration (build.json).`,
+	},
+	{
+		Category: CategoryCocoaPods,
+		Pattern:  regexp.MustCompile(`CocoaPods could not find compatible versions for pod|Unable to find a specification for|pod: command not found|CocoaPods not installed`),
+		Hint:     `CocoaPods failed to install the pods of the iOS project. Run pod repo update (or pod install --repo-update in the ios project), and check that CocoaPods is installed.`,
+	},
+	{
+		Category: CategoryESMRequire,
+		Pattern:  regexp.MustCompile(`ERR_REQUIRE_ESM`),
+		Hint:     `A dependency is published as an ES module only, so it can't be loaded with require(). Use a Node.js version, which supports it, or pin the dependency to its last CommonJS release.`,
+	},
+	{
+		Category: CategoryNodeVersion,
+		Pattern:  regexp.MustCompile(`The engine "node" is incompatible with this module|Node\.js version v?[\d.]+ detected|(?i)requires a minimum node\.js version|EBADENGINE`),
+		Hint:     `The active Node.js version is not supported by the project's dependencies. Switch to a supported version (for example with nvm, or a stack with it) before the build.`,
+	},
+	{
+		Category: CategoryMissingNodeModule,
+		Pattern:  regexp.MustCompile(`Cannot find module '[^']+'|Error: Cannot find module`),
+		Hint:     `A Node.js module is missing. Check that the dependencies are installed (npm ci or yarn install) and that the module is listed in package.json.`,
+	},
+	{
+		Category: CategoryNotCordovaProject,
+		Pattern:  regexp.MustCompile(`Current working directory is not a Cordova-based project`),
+		Hint:     `cordova didn't find a Cordova project in the working directory. Set the workdir input to the directory of config.xml, and add the platforms (ionic cordova platform add <platform>), if the platforms directory is not committed.`,
+	},
+	{
+		Category: CategoryCordovaPlatformMissing,
+		Pattern:  regexp.MustCompile(`(?i)platform "?(ios|android)"? not added|platform (ios|android) is not added`),
+		Hint:     `The platform is not added to the Cordova project. Add it with ionic cordova platform add <platform>, or list it in the config.xml and package.json of the project.`,
+	},
+	{
+		Category: CategoryNotIonicProject,
+		Pattern:  regexp.MustCompile(`(?i)can only be run in an ionic project directory|not an ionic project|ionic\.config\.json file not found`),
+		Hint:     `ionic didn't find an Ionic project in the working directory. Set the workdir input to the directory of ionic.config.json.`,
+	},
+	{
+		Category: CategoryIonicAuthentication,
+		Pattern:  regexp.MustCompile(`(?i)you must be logged in|invalid credentials|token is (invalid|expired)`),
+		Hint:     `The ionic authentication failed. Check the ionic_token input (or the ionic_username and ionic_password inputs).`,
+	},
+}
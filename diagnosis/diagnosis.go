@@ -0,0 +1,45 @@
+// Package diagnosis matches the output of the failed commands against a catalog of known failures,
+// to tell the cause of a failed build and how to fix it.
+package diagnosis
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// Signature describes a known failure
+type Signature struct {
+	// Category is a short, stable name of the failure
+	Category string
+	// Pattern matches a line of the output of the failed command
+	Pattern *regexp.Regexp
+	// Hint tells how to fix the failure
+	Hint string
+}
+
+// Diagnosis is a known failure found in the output of a failed command
+type Diagnosis struct {
+	Signature
+	// Line is the matching line of the output
+	Line string
+}
+
+// Diagnose returns the first signature of the catalog, which matches a line of the output
+func Diagnose(catalog []Signature, output string) (Diagnosis, bool) {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	for _, signature := range catalog {
+		for _, line := range lines {
+			if signature.Pattern.MatchString(line) {
+				return Diagnosis{Signature: signature, Line: strings.TrimSpace(line)}, true
+			}
+		}
+	}
+	return Diagnosis{}, false
+}
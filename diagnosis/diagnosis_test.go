@@ -0,0 +1,118 @@
+package diagnosis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Diagnose(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		wantCategory string
+		wantLine     string
+	}{
+		{
+			name:         "missing Android SDK platform",
+			output:       "FAILURE: Build failed with an exception.\n* What went wrong:\n  Failed to find target with hash string 'android-33' in: /opt/android-sdk\n",
+			wantCategory: CategoryAndroidSDKPlatform,
+			wantLine:     "Failed to find target with hash string 'android-33' in: /opt/android-sdk",
+		},
+		{
+			name:         "JDK mismatch",
+			output:       "> Failed to apply plugin 'com.android.internal.application'.\n   > Android Gradle plugin requires Java 17 to run. You are currently using Java 11.\n",
+			wantCategory: CategoryJDKMismatch,
+			wantLine:     "> Android Gradle plugin requires Java 17 to run. You are currently using Java 11.",
+		},
+		{
+			name:         "code signing identity not found",
+			output:       "error: No signing certificate \"iOS Distribution\" found: No \"iOS Distribution\" signing certificate matching team ID \"ABCDE12345\" with a private key was found. (in target 'App' from project 'App')\n** ARCHIVE FAILED **\n",
+			wantCategory: CategoryCodeSigningIdentity,
+			wantLine:     "error: No signing certificate \"iOS Distribution\" found: No \"iOS Distribution\" signing certificate matching team ID \"ABCDE12345\" with a private key was found. (in target 'App' from project 'App')",
+		},
+		{
+			name:         "ERR_REQUIRE_ESM",
+			output:       "[error] Error [ERR_REQUIRE_ESM]: require() of ES Module /project/node_modules/chalk/source/index.js not supported.\n",
+			wantCategory: CategoryESMRequire,
+			wantLine:     "[error] Error [ERR_REQUIRE_ESM]: require() of ES Module /project/node_modules/chalk/source/index.js not supported.",
+		},
+		{
+			name:         "not a Cordova project",
+			output:       "> cordova build android --release\n[ERROR] An error occurred while running subprocess cordova.\n        Current working directory is not a Cordova-based project.\n",
+			wantCategory: CategoryNotCordovaProject,
+			wantLine:     "Current working directory is not a Cordova-based project.",
+		},
+		{
+			name:         "the more specific signature wins",
+			output:       "Error: Cannot find module 'foo'\nError [ERR_REQUIRE_ESM]: require() of ES Module\n",
+			wantCategory: CategoryESMRequire,
+			wantLine:     "Error [ERR_REQUIRE_ESM]: require() of ES Module",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Diagnose(Catalog, tt.output)
+			require.True(t, ok)
+			require.Equal(t, tt.wantCategory, got.Category)
+			require.Equal(t, tt.wantLine, got.Line)
+			require.NotEmpty(t, got.Hint)
+		})
+	}
+
+	_, ok := Diagnose(Catalog, "BUILD FAILED\nexit status 1\n")
+	require.False(t, ok)
+}
+
+func Test_Catalog(t *testing.T) {
+	categories := map[string]bool{}
+	for _, signature := range Catalog {
+		require.False(t, categories[signature.Category], "duplicated category: %s", signature.Category)
+		categories[signature.Category] = true
+		require.NotEmpty(t, signature.Hint, signature.Category)
+	}
+}
+
+func Test_Runner(t *testing.T) {
+	fake := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		if cmd.String() == "ionic cordova build ios" {
+			return "** ARCHIVE FAILED **", errors.New("exit status 65")
+		}
+		return "done", nil
+	})
+	r := NewRunner(fake)
+
+	var out strings.Builder
+	require.NoError(t, r.Run(runner.New("ionic", "cordova", "prepare").SetStdout(&out).SetStderr(&out)))
+	err := r.Run(runner.New("ionic", "cordova", "build", "ios").SetStdout(&out).SetStderr(&out))
+	_, outputErr := r.RunAndReturnTrimmedCombinedOutput(runner.New("ionic", "cordova", "build", "ios"))
+	_, versionErr := r.RunAndReturnTrimmedCombinedOutput(runner.New("ionic", "-v"))
+	require.NoError(t, versionErr)
+
+	// the output is still written to the command's stdout
+	require.Equal(t, "done\n** ARCHIVE FAILED **\n", out.String())
+
+	want := &CommandError{Command: `ionic "cordova" "build" "ios"`, Output: "** ARCHIVE FAILED **\n", Err: errors.New("exit status 65")}
+	require.Equal(t, want, err)
+	require.EqualError(t, err, "exit status 65")
+	require.Equal(t, &CommandError{Command: want.Command, Output: "** ARCHIVE FAILED **", Err: want.Err}, outputErr)
+
+	// the output can be found in the step's error, which wraps the command's error
+	var commandErr *CommandError
+	require.True(t, errors.As(fmt.Errorf("ionic build failed, error: %w", err), &commandErr))
+	require.Equal(t, want, commandErr)
+}
+
+func Test_tailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 8}
+	for _, chunk := range []string{"line 1\n", "line 2\n"} {
+		n, err := b.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+	require.Equal(t, "\nline 2\n", b.String())
+}
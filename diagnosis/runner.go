@@ -0,0 +1,104 @@
+package diagnosis
+
+import (
+	"io"
+	"sync"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+// maxCapturedOutput is the size of the end of a command's output, which is kept for the diagnosis
+const maxCapturedOutput = 1024 * 1024
+
+// CommandError is the error of a failed command, with the end of the command's output.
+// Its message is the message of the command's error, so it can be wrapped into the step's error
+// and diagnosed only if the failed command failed the step.
+type CommandError struct {
+	Command string
+	Output  string
+	Err     error
+}
+
+// Error returns the message of the command's error
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the command's error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Runner is a runner.Runner, which captures the output of the commands executed by the wrapped Runner,
+// and returns the errors of the failed commands as *CommandError.
+type Runner struct {
+	runner.Runner
+}
+
+// NewRunner returns a Runner, which wraps the runner
+func NewRunner(r runner.Runner) *Runner {
+	return &Runner{Runner: r}
+}
+
+// Run runs the command with its output captured, in addition to the command's stdout and stderr
+func (r *Runner) Run(cmd *runner.Command) error {
+	output := &tailBuffer{max: maxCapturedOutput}
+
+	captured := *cmd
+	captured.Stdout = teeWriter(cmd.Stdout, output)
+	if cmd.Stderr == cmd.Stdout {
+		// the command's stdout and stderr are written by a single pipe, if they are the same
+		captured.Stderr = captured.Stdout
+	} else {
+		captured.Stderr = teeWriter(cmd.Stderr, output)
+	}
+
+	if err := r.Runner.Run(&captured); err != nil {
+		return &CommandError{Command: cmd.PrintableCommandArgs(), Output: output.String(), Err: err}
+	}
+	return nil
+}
+
+// RunAndReturnTrimmedCombinedOutput runs the command and attaches its output to the error, if it fails
+func (r *Runner) RunAndReturnTrimmedCombinedOutput(cmd *runner.Command) (string, error) {
+	out, err := r.Runner.RunAndReturnTrimmedCombinedOutput(cmd)
+	if err != nil {
+		return out, &CommandError{Command: cmd.PrintableCommandArgs(), Output: out, Err: err}
+	}
+	return out, nil
+}
+
+func teeWriter(w io.Writer, output io.Writer) io.Writer {
+	if w == nil {
+		return output
+	}
+	return io.MultiWriter(w, output)
+}
+
+// tailBuffer keeps the last max bytes of the written content
+type tailBuffer struct {
+	max int
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write appends the content, and drops the beginning of the buffer over max bytes
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append([]byte{}, b.buf[len(b.buf)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the kept content
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.buf)
+}
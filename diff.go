@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/android"
+	"github.com/bitrise-steplib/steps-ionic-archive/breakdown"
+	"github.com/bitrise-steplib/steps-ionic-archive/ios"
+)
+
+const (
+	artifactDiffPathEnvKey = "BITRISE_IONIC_ARTIFACT_DIFF_PATH"
+	artifactDiffFileName   = "ionic-archive-diff.txt"
+)
+
+// isArchive tells whether the artifact type is compared with the previous build (ipa, apk, aab)
+func isArchive(typ string) bool {
+	return typ == "ipa" || typ == "apk" || typ == "aab"
+}
+
+// readPreviousArtifacts reads the artifacts of a previous build, either from its artifact manifest (.json) or from an ipa, apk or aab
+func readPreviousArtifacts(pth string) ([]manifestArtifact, error) {
+	if filepath.Ext(pth) == ".json" {
+		content, err := os.ReadFile(pth)
+		if err != nil {
+			return nil, err
+		}
+		var previous artifactManifest
+		if err := json.Unmarshal(content, &previous); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", pth, err)
+		}
+		return previous.Artifacts, nil
+	}
+
+	artifact, err := describeArtifact(pth)
+	if err != nil {
+		return nil, err
+	}
+	return []manifestArtifact{artifact}, nil
+}
+
+// describeArtifact reads the archive the same way as the exported ones are recorded in the artifact manifest
+func describeArtifact(pth string) (manifestArtifact, error) {
+	typ, platform := artifactType(pth)
+	if !isArchive(typ) {
+		return manifestArtifact{}, fmt.Errorf("not an ipa, apk, aab or artifact manifest: %s", pth)
+	}
+	info, err := os.Stat(pth)
+	if err != nil {
+		return manifestArtifact{}, err
+	}
+	report, err := breakdown.Archive(pth)
+	if err != nil {
+		return manifestArtifact{}, fmt.Errorf("failed to read %s: %s", pth, err)
+	}
+
+	m := artifactManifest{Artifacts: []manifestArtifact{{Platform: platform, Type: typ, DeployedPath: pth, Size: info.Size()}}}
+	m.setBreakdown(pth, report)
+	if typ == "ipa" {
+		if ipa, err := ios.InspectIPA(pth); err != nil {
+			log.Warnf("Failed to inspect %s, error: %s", pth, err)
+		} else {
+			m.setIOSApp(pth, ipa)
+		}
+	} else {
+		if androidManifest, err := android.ReadManifest(pth); err != nil {
+			log.Warnf("Failed to read the AndroidManifest.xml of %s, error: %s", pth, err)
+		} else {
+			m.setAndroidManifest(pth, androidManifest)
+		}
+	}
+	return m.Artifacts[0], nil
+}
+
+// compareWithPrevious writes the changes of the exported archives compared to the previous build into the dir and returns its path
+func compareWithPrevious(previousPth string, manifest artifactManifest, dir string) (string, error) {
+	previous, err := readPreviousArtifacts(previousPth)
+	if err != nil {
+		return "", err
+	}
+
+	report := diffReport(previous, manifest.Artifacts)
+	log.Printf("%s", report)
+
+	pth := filepath.Join(dir, artifactDiffFileName)
+	if err := os.WriteFile(pth, []byte(report+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
+
+// diffReport describes the changes of the current archives compared to their previous versions.
+// The previous version of an archive is the previous one with the same type and file name, or else the first one with the same type.
+func diffReport(previous, current []manifestArtifact) string {
+	matched := map[int]bool{}
+	findPrevious := func(artifact manifestArtifact) (manifestArtifact, bool) {
+		candidate := -1
+		for i, p := range previous {
+			if matched[i] || p.Type != artifact.Type {
+				continue
+			}
+			if filepath.Base(p.DeployedPath) == filepath.Base(artifact.DeployedPath) {
+				candidate = i
+				break
+			}
+			if candidate == -1 {
+				candidate = i
+			}
+		}
+		if candidate == -1 {
+			return manifestArtifact{}, false
+		}
+		matched[candidate] = true
+		return previous[candidate], true
+	}
+
+	var lines []string
+	for _, artifact := range current {
+		if !isArchive(artifact.Type) {
+			continue
+		}
+		name := filepath.Base(artifact.DeployedPath)
+		previousArtifact, ok := findPrevious(artifact)
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%s: no previous %s to compare with", name, artifact.Type))
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("%s (previous: %s)", name, filepath.Base(previousArtifact.DeployedPath)))
+		changes := diffArtifacts(previousArtifact, artifact)
+		if len(changes) == 0 {
+			changes = []string{"no changes"}
+		}
+		for _, change := range changes {
+			lines = append(lines, "  "+change)
+		}
+	}
+	for i, p := range previous {
+		if !matched[i] && isArchive(p.Type) {
+			lines = append(lines, fmt.Sprintf("%s: not built anymore", filepath.Base(p.DeployedPath)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffArtifacts lists the changed version fields, the size delta (per category), and the added and removed permissions, native libraries and web assets
+func diffArtifacts(previous, current manifestArtifact) []string {
+	var changes []string
+
+	previousFields := versionFields(previous)
+	currentFields := versionFields(current)
+	for _, field := range mergeKeys(currentFields, previousFields) {
+		if before, after := fieldValue(previousFields, field), fieldValue(currentFields, field); before != after {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", field, before, after))
+		}
+	}
+
+	if previous.Size != current.Size {
+		changes = append(changes, fmt.Sprintf("size: %s -> %s (%s)", formatSize(previous.Size), formatSize(current.Size), formatSizeDelta(current.Size-previous.Size)))
+	}
+	previousCategories := categorySizes(previous.SizeBreakdown)
+	currentCategories := categorySizes(current.SizeBreakdown)
+	for _, category := range mergeNames(categoryNames(current.SizeBreakdown), categoryNames(previous.SizeBreakdown)) {
+		if before, after := previousCategories[category], currentCategories[category]; before != after {
+			changes = append(changes, fmt.Sprintf("  %s: %s -> %s (%s)", category, formatSize(before), formatSize(after), formatSizeDelta(after-before)))
+		}
+	}
+
+	var previousPermissions, currentPermissions []string
+	if previous.AndroidManifest != nil {
+		previousPermissions = previous.AndroidManifest.Permissions
+	}
+	if current.AndroidManifest != nil {
+		currentPermissions = current.AndroidManifest.Permissions
+	}
+	changes = append(changes, diffSet("permissions", previousPermissions, currentPermissions)...)
+	changes = append(changes, diffSet("native libs", previous.NativeLibs, current.NativeLibs)...)
+	changes = append(changes, diffSet("web assets", previous.WebAssets, current.WebAssets)...)
+	return changes
+}
+
+// versionFields returns the version related fields of the artifact, in the order they are reported
+func versionFields(artifact manifestArtifact) [][2]string {
+	var fields [][2]string
+	switch {
+	case artifact.AndroidManifest != nil:
+		m := artifact.AndroidManifest
+		fields = append(fields,
+			[2]string{"package name", m.PackageName},
+			[2]string{"version code", strconv.Itoa(m.VersionCode)},
+			[2]string{"version name", m.VersionName},
+			[2]string{"min SDK version", strconv.Itoa(m.MinSDK)},
+			[2]string{"target SDK version", strconv.Itoa(m.TargetSDK)},
+		)
+	case artifact.AndroidVariant != nil:
+		v := artifact.AndroidVariant
+		fields = append(fields,
+			[2]string{"version code", strconv.Itoa(v.VersionCode)},
+			[2]string{"version name", v.VersionName},
+		)
+	case artifact.IOSApp != nil:
+		app := artifact.IOSApp
+		fields = append(fields,
+			[2]string{"bundle id", app.BundleID},
+			[2]string{"version", app.Version},
+			[2]string{"build number", app.BuildNumber},
+		)
+		if app.Profile != nil {
+			fields = append(fields,
+				[2]string{"team id", app.Profile.TeamID},
+				[2]string{"export method", app.Profile.ExportMethod},
+			)
+		}
+	}
+	return fields
+}
+
+func fieldValue(fields [][2]string, name string) string {
+	for _, field := range fields {
+		if field[0] == name {
+			return field[1]
+		}
+	}
+	return "(none)"
+}
+
+// mergeKeys returns the names of the current fields followed by the names only the previous fields have
+func mergeKeys(current, previous [][2]string) []string {
+	return mergeNames(fieldNames(current), fieldNames(previous))
+}
+
+// mergeNames returns the current names followed by the names only the previous ones have
+func mergeNames(current, previous []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range append(append([]string{}, current...), previous...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func fieldNames(fields [][2]string) []string {
+	var names []string
+	for _, field := range fields {
+		names = append(names, field[0])
+	}
+	return names
+}
+
+func categoryNames(categories []breakdown.Category) []string {
+	var names []string
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	return names
+}
+
+func categorySizes(categories []breakdown.Category) map[string]int64 {
+	sizes := map[string]int64{}
+	for _, category := range categories {
+		sizes[category.Name] = category.Size
+	}
+	return sizes
+}
+
+// diffSet lists the added and the removed items
+func diffSet(name string, previous, current []string) []string {
+	inPrevious := map[string]bool{}
+	for _, item := range previous {
+		inPrevious[item] = true
+	}
+	inCurrent := map[string]bool{}
+	for _, item := range current {
+		inCurrent[item] = true
+	}
+
+	var added, removed []string
+	for item := range inCurrent {
+		if !inPrevious[item] {
+			added = append(added, item)
+		}
+	}
+	for item := range inPrevious {
+		if !inCurrent[item] {
+			removed = append(removed, item)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("%s added: %s", name, strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("%s removed: %s", name, strings.Join(removed, ", ")))
+	}
+	return changes
+}
+
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatSize(-delta)
+	}
+	return "+" + formatSize(delta)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/breakdown"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_diffArtifacts(t *testing.T) {
+	previousAPK := manifestArtifact{
+		Type:         "apk",
+		DeployedPath: "/previous/app-release.apk",
+		Size:         2000000,
+		AndroidManifest: &manifestAndroidManifest{
+			PackageName: "io.ionic.starter",
+			VersionCode: 10202,
+			VersionName: "1.2.2",
+			MinSDK:      22,
+			TargetSDK:   33,
+			Permissions: []string{"android.permission.INTERNET", "android.permission.CAMERA"},
+		},
+		SizeBreakdown: []breakdown.Category{
+			{Name: breakdown.WebAssets, Size: 1500000},
+			{Name: breakdown.Dex, Size: 500000},
+		},
+		NativeLibs: []string{"arm64-v8a/libsqlite.so"},
+		WebAssets:  []string{"index.html", "main.1a2b.js"},
+	}
+	currentAPK := manifestArtifact{
+		Type:         "apk",
+		DeployedPath: "/deploy/app-release.apk",
+		Size:         2600000,
+		AndroidManifest: &manifestAndroidManifest{
+			PackageName: "io.ionic.starter",
+			VersionCode: 10203,
+			VersionName: "1.2.3",
+			MinSDK:      22,
+			TargetSDK:   34,
+			Permissions: []string{"android.permission.INTERNET", "android.permission.ACCESS_FINE_LOCATION"},
+		},
+		SizeBreakdown: []breakdown.Category{
+			{Name: breakdown.WebAssets, Size: 1500000},
+			{Name: breakdown.Dex, Size: 400000},
+			{Name: breakdown.NativeLibs + " (arm64-v8a)", Size: 700000},
+		},
+		NativeLibs: []string{"arm64-v8a/libsqlite.so", "arm64-v8a/libmaps.so"},
+		WebAssets:  []string{"index.html", "main.3c4d.js"},
+	}
+
+	tests := []struct {
+		name     string
+		previous manifestArtifact
+		current  manifestArtifact
+		want     []string
+	}{
+		{name: "same artifact", previous: currentAPK, current: currentAPK, want: nil},
+		{
+			name:     "changed apk",
+			previous: previousAPK,
+			current:  currentAPK,
+			want: []string{
+				"version code: 10202 -> 10203",
+				"version name: 1.2.2 -> 1.2.3",
+				"target SDK version: 33 -> 34",
+				"size: 2.0 MB -> 2.6 MB (+600.0 kB)",
+				"  dex: 500.0 kB -> 400.0 kB (-100.0 kB)",
+				"  native libs (arm64-v8a): 0 B -> 700.0 kB (+700.0 kB)",
+				"permissions added: android.permission.ACCESS_FINE_LOCATION",
+				"permissions removed: android.permission.CAMERA",
+				"native libs added: arm64-v8a/libmaps.so",
+				"web assets added: main.3c4d.js",
+				"web assets removed: main.1a2b.js",
+			},
+		},
+		{
+			name:     "ipa",
+			previous: manifestArtifact{Type: "ipa", Size: 100, IOSApp: &manifestIOSApp{BundleID: "io.ionic.starter", Version: "1.2.3", BuildNumber: "41", Profile: &manifestIOSProfile{TeamID: "ABCDE12345", ExportMethod: "development"}}},
+			current:  manifestArtifact{Type: "ipa", Size: 100, IOSApp: &manifestIOSApp{BundleID: "io.ionic.starter", Version: "1.2.3", BuildNumber: "42", Profile: &manifestIOSProfile{TeamID: "ABCDE12345", ExportMethod: "app-store"}}},
+			want: []string{
+				"build number: 41 -> 42",
+				"export method: development -> app-store",
+			},
+		},
+		{
+			name:     "previous artifact without details",
+			previous: manifestArtifact{Type: "ipa", Size: 100},
+			current:  manifestArtifact{Type: "ipa", Size: 100, IOSApp: &manifestIOSApp{BundleID: "io.ionic.starter", Version: "1.2.3", BuildNumber: "42"}},
+			want: []string{
+				"bundle id: (none) -> io.ionic.starter",
+				"version: (none) -> 1.2.3",
+				"build number: (none) -> 42",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, diffArtifacts(tt.previous, tt.current))
+		})
+	}
+}
+
+func Test_diffReport(t *testing.T) {
+	previous := []manifestArtifact{
+		{Type: "apk", DeployedPath: "/previous/app-debug.apk", Size: 100},
+		{Type: "apk", DeployedPath: "/previous/app-release.apk", Size: 200},
+		{Type: "ipa", DeployedPath: "/previous/app.ipa", Size: 300},
+		{Type: "dSYM", DeployedPath: "/previous/app.dSYM", Size: 400},
+	}
+	current := []manifestArtifact{
+		{Type: "apk", DeployedPath: "/deploy/app-release.apk", Size: 200},
+		{Type: "aab", DeployedPath: "/deploy/app-release.aab", Size: 500},
+		{Type: "dSYM", DeployedPath: "/deploy/app.dSYM", Size: 600},
+	}
+
+	want := "app-release.apk (previous: app-release.apk)\n" +
+		"  no changes\n" +
+		"app-release.aab: no previous aab to compare with\n" +
+		"app-debug.apk: not built anymore\n" +
+		"app.ipa: not built anymore"
+	require.Equal(t, want, diffReport(previous, current))
+}
@@ -0,0 +1,82 @@
+// Package digest calculates the SHA-256 digests of the build outputs
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File returns the size and the hex encoded SHA-256 digest of the file
+func File(pth string) (int64, string, error) {
+	f, err := os.Open(pth)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Dir returns the total size of the files in the directory and a digest,
+// which is the SHA-256 of the `<sha256>  <relative path>` lines of the files in lexical order (symlinks are hashed by their target).
+func Dir(dir string) (int64, string, error) {
+	var lines []string
+	var size int64
+	if err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, pth)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(pth)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256([]byte(link))
+			lines = append(lines, hex.EncodeToString(sum[:])+"  "+rel)
+		case info.Mode().IsRegular():
+			fileSize, digest, err := File(pth)
+			if err != nil {
+				return err
+			}
+			size += fileSize
+			lines = append(lines, digest+"  "+rel)
+		}
+		return nil
+	}); err != nil {
+		return 0, "", err
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return size, hex.EncodeToString(sum[:]), nil
+}
+
+// Path returns the digest of the file or the directory (see Dir)
+func Path(pth string) (int64, string, error) {
+	info, err := os.Stat(pth)
+	if err != nil {
+		return 0, "", err
+	}
+	if info.IsDir() {
+		return Dir(pth)
+	}
+	return File(pth)
+}
@@ -0,0 +1,49 @@
+package digest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_File(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "app-release.apk")
+	require.NoError(t, os.WriteFile(pth, []byte("apk"), 0600))
+
+	size, digest, err := File(pth)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), size)
+	require.Equal(t, "dd37c2d7274f7ea982cb83390c36918fee9ce8889073c44b68cdc00bdb8c3e04", digest)
+}
+
+func Test_Dir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		pth := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(pth), 0755))
+		require.NoError(t, os.WriteFile(pth, []byte(content), 0600))
+	}
+
+	writeFile("a", "a")
+	writeFile("b/c", "c")
+	size, digest, err := Dir(dir)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), size)
+
+	// the digest depends on the content and the paths
+	writeFile("b/c", "d")
+	_, changedContentDigest, err := Dir(dir)
+	require.NoError(t, err)
+	require.NotEqual(t, digest, changedContentDigest)
+
+	require.NoError(t, os.Rename(filepath.Join(dir, "b"), filepath.Join(dir, "e")))
+	_, renamedDigest, err := Dir(dir)
+	require.NoError(t, err)
+	require.NotEqual(t, changedContentDigest, renamedDigest)
+
+	_, pathDigest, err := Path(dir)
+	require.NoError(t, err)
+	require.Equal(t, renamedDigest, pathDigest)
+}
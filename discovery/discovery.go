@@ -0,0 +1,179 @@
+// Package discovery finds the outputs of a build by comparing the candidate outputs before and after the build,
+// instead of comparing modification times to the build start, which is unreliable with clock skew, restored caches and tools preserving timestamps.
+// An output counts as changed if it is new, its content changed or it was rewritten (replaced or its modification time changed) during the build,
+// so that the reproducible outputs of a deterministic build are found too.
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/digest"
+)
+
+// Rule tells where a build writes its outputs of a kind
+type Rule struct {
+	// Dir is searched recursively for the outputs
+	Dir string
+	// Ext is the extension of the outputs without the leading dot (ipa, app, dSYM, apk, aab)
+	Ext string
+}
+
+// bundleExts are the extensions of directory outputs and intermediates, their content is not searched,
+// so that for example the .app in an .xcarchive or the frameworks of an .app are not picked up.
+var bundleExts = map[string]bool{".app": true, ".dSYM": true, ".xcarchive": true, ".framework": true, ".appex": true, ".bundle": true}
+
+// Output is a candidate output found after the build
+type Output struct {
+	Path string
+	Ext  string
+	// Changed is false if the output existed with the same content before the build and it was not rewritten by the build
+	Changed bool
+}
+
+// Outputs are the candidate outputs found after the build, in lexical order of their paths
+type Outputs []Output
+
+// Find returns the paths of the outputs with the extension, which are new, changed or rewritten since the snapshot
+func (o Outputs) Find(ext string) []string {
+	return o.paths(ext, true)
+}
+
+// Stale returns the paths of the outputs with the extension, which existed before the snapshot and were not rewritten,
+// like the outputs of an earlier build or restored from a cache.
+func (o Outputs) Stale(ext string) []string {
+	return o.paths(ext, false)
+}
+
+func (o Outputs) paths(ext string, changed bool) []string {
+	var paths []string
+	for _, output := range o {
+		if output.Ext == ext && output.Changed == changed {
+			paths = append(paths, output.Path)
+		}
+	}
+	return paths
+}
+
+type entry struct {
+	ext    string
+	size   int64
+	digest string
+	info   os.FileInfo
+}
+
+// rewritten tells if the path was replaced by an other file or its modification time changed since the previous entry
+func (e entry) rewritten(previous entry) bool {
+	return !os.SameFile(previous.info, e.info) || !previous.info.ModTime().Equal(e.info.ModTime())
+}
+
+// Discoverer snapshots the candidate outputs before the build and diffs them afterward
+type Discoverer struct {
+	rules  []Rule
+	before map[string]entry
+}
+
+// New returns a Discoverer searching for the outputs based on the rules
+func New(rules ...Rule) *Discoverer {
+	return &Discoverer{rules: rules, before: map[string]entry{}}
+}
+
+// Snapshot records the path, size, digest and file info of the candidate outputs, it is called before the build
+func (d *Discoverer) Snapshot() error {
+	entries, err := d.scan()
+	if err != nil {
+		return err
+	}
+	d.before = entries
+	return nil
+}
+
+// Outputs returns the candidate outputs after the build, marking the ones not present in the snapshot with the same content
+// or rewritten since the snapshot as changed
+func (d *Discoverer) Outputs() (Outputs, error) {
+	entries, err := d.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs Outputs
+	for pth, e := range entries {
+		previous, existed := d.before[pth]
+		outputs = append(outputs, Output{
+			Path:    pth,
+			Ext:     e.ext,
+			Changed: !existed || previous.size != e.size || previous.digest != e.digest || e.rewritten(previous),
+		})
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].Path < outputs[j].Path })
+	return outputs, nil
+}
+
+// Dirs returns the searched directories
+func (d *Discoverer) Dirs() []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, rule := range d.rules {
+		if !seen[rule.Dir] {
+			seen[rule.Dir] = true
+			dirs = append(dirs, rule.Dir)
+		}
+	}
+	return dirs
+}
+
+func (d *Discoverer) scan() (map[string]entry, error) {
+	extsByDir := map[string]map[string]bool{}
+	for _, rule := range d.rules {
+		if extsByDir[rule.Dir] == nil {
+			extsByDir[rule.Dir] = map[string]bool{}
+		}
+		extsByDir[rule.Dir]["."+rule.Ext] = true
+	}
+
+	entries := map[string]entry{}
+	for _, dir := range d.Dirs() {
+		exts := extsByDir[dir]
+		if info, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			continue
+		}
+
+		if err := filepath.Walk(dir, func(pth string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if pth == dir {
+				return nil
+			}
+
+			ext := filepath.Ext(pth)
+			if exts[ext] {
+				size, sum, err := digest.Path(pth)
+				if os.IsNotExist(err) {
+					// a broken symlink
+					return nil
+				} else if err != nil {
+					return err
+				}
+				// the file info of the symlink target, as the bundle or package is compared
+				fileInfo, err := os.Stat(pth)
+				if err != nil {
+					return err
+				}
+				entries[pth] = entry{ext: ext[1:], size: size, digest: sum, info: fileInfo}
+			}
+			if info.IsDir() && bundleExts[ext] {
+				return filepath.SkipDir
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
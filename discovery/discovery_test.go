@@ -0,0 +1,99 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		pth := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(pth), 0755))
+		require.NoError(t, os.WriteFile(pth, []byte(content), 0600))
+	}
+}
+
+func Test_Discoverer(t *testing.T) {
+	dir := t.TempDir()
+	iosDir := filepath.Join(dir, "ios")
+	androidDir := filepath.Join(dir, "android")
+
+	writeFiles(t, dir, map[string]string{
+		"ios/Unchanged.ipa":            "ipa",
+		"ios/Rewritten.ipa":            "same ipa",
+		"ios/Replaced.ipa":             "same ipa",
+		"ios/Rebuilt.ipa":              "old ipa",
+		"ios/App.app/App":              "old app",
+		"android/release/app.apk":      "apk",
+		"android/release/app.aab":      "not searched",
+		"outside/release/outside.apk":  "not searched",
+		"ios/Removed.app.dSYM/Info":    "dsym",
+		"ios/Archive.xcarchive/A.ipa":  "intermediate",
+		"ios/App.app/Frameworks/F.ipa": "bundle content",
+	})
+
+	d := New(
+		Rule{Dir: iosDir, Ext: "ipa"},
+		Rule{Dir: iosDir, Ext: "app"},
+		Rule{Dir: iosDir, Ext: "dSYM"},
+		Rule{Dir: androidDir, Ext: "apk"},
+		Rule{Dir: filepath.Join(dir, "missing"), Ext: "apk"},
+	)
+	require.NoError(t, d.Snapshot())
+
+	// a restored cache or a timestamp preserving tool may set any modification time
+	past := time.Now().Add(-24 * time.Hour)
+	writeFiles(t, dir, map[string]string{
+		"ios/Rebuilt.ipa":   "new ipa",
+		"ios/New.ipa":       "ipa",
+		"ios/Rewritten.ipa": "same ipa",
+		"ios/App.app/App":   "new app",
+		"android/debug/app": "not an apk",
+		"ios/tmp/Replaced":  "same ipa",
+	})
+	require.NoError(t, os.Chtimes(filepath.Join(iosDir, "New.ipa"), past, past))
+	// a deterministic build writes the same content
+	require.NoError(t, os.Chtimes(filepath.Join(iosDir, "Rewritten.ipa"), past, past))
+	// and may replace the output with a file having the same content and modification time
+	replacedInfo, err := os.Stat(filepath.Join(iosDir, "Replaced.ipa"))
+	require.NoError(t, err)
+	require.NoError(t, os.Chtimes(filepath.Join(iosDir, "tmp", "Replaced"), replacedInfo.ModTime(), replacedInfo.ModTime()))
+	require.NoError(t, os.Rename(filepath.Join(iosDir, "tmp", "Replaced"), filepath.Join(iosDir, "Replaced.ipa")))
+	require.NoError(t, os.RemoveAll(filepath.Join(iosDir, "Removed.app.dSYM")))
+
+	outputs, err := d.Outputs()
+	require.NoError(t, err)
+	require.Equal(t, Outputs{
+		{Path: filepath.Join(androidDir, "release", "app.apk"), Ext: "apk", Changed: false},
+		{Path: filepath.Join(iosDir, "App.app"), Ext: "app", Changed: true},
+		{Path: filepath.Join(iosDir, "New.ipa"), Ext: "ipa", Changed: true},
+		{Path: filepath.Join(iosDir, "Rebuilt.ipa"), Ext: "ipa", Changed: true},
+		{Path: filepath.Join(iosDir, "Replaced.ipa"), Ext: "ipa", Changed: true},
+		{Path: filepath.Join(iosDir, "Rewritten.ipa"), Ext: "ipa", Changed: true},
+		{Path: filepath.Join(iosDir, "Unchanged.ipa"), Ext: "ipa", Changed: false},
+	}, outputs)
+
+	require.Equal(t, []string{iosDir, androidDir, filepath.Join(dir, "missing")}, d.Dirs())
+}
+
+func Test_Outputs_Find(t *testing.T) {
+	outputs := Outputs{
+		{Path: "/android/release/app.apk", Ext: "apk", Changed: false},
+		{Path: "/ios/New.ipa", Ext: "ipa", Changed: true},
+		{Path: "/ios/Unchanged.ipa", Ext: "ipa", Changed: false},
+	}
+
+	require.Equal(t, []string{"/ios/New.ipa"}, outputs.Find("ipa"))
+	require.Equal(t, []string{"/ios/Unchanged.ipa"}, outputs.Stale("ipa"))
+
+	// the outputs, which were not rewritten by the build, are not found
+	require.Empty(t, outputs.Find("apk"))
+	require.Equal(t, []string{"/android/release/app.apk"}, outputs.Stale("apk"))
+
+	require.Empty(t, outputs.Find("aab"))
+	require.Empty(t, outputs.Stale("aab"))
+}
@@ -0,0 +1,786 @@
+package e2e
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/signing"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this package run the compiled step against scripted fake ionic, cordova, npm, yarn and envman
+// binaries (testdata/fakebin), which produce fixture build outputs, so that the artifact discovery and
+// the env exports are covered without network access, Xcode or the Android SDK.
+
+var stepPath string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	if testing.Short() || runtime.GOOS == "windows" {
+		return m.Run()
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ionic-archive-e2e")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	stepPath = filepath.Join(tmpDir, "step")
+	cmd := exec.Command("go", "build", "-o", stepPath, "..")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Printf("Failed to build the step: %s, output: %s\n", err, out)
+		return 1
+	}
+
+	return m.Run()
+}
+
+var cordovaProject = map[string]string{
+	"ionic.config.json": `{"name": "HelloCordova", "type": "angular", "integrations": {"cordova": {}}}`,
+	"config.xml":        `<widget id="io.ionic.starter" version="1.0.0"><name>HelloCordova</name></widget>`,
+	"package.json":      `{"name": "hello-cordova", "dependencies": {"@angular/core": "^15.0.0"}}`,
+}
+
+// harness is a project directory, a deploy directory and the fake CLI state of a step run
+type harness struct {
+	t *testing.T
+
+	workDir   string
+	deployDir string
+	envmanDir string
+	cliLog    string
+
+	// inputs are the step inputs, passed as envs
+	inputs map[string]string
+	// fakeEnvs configure the fake CLIs (see testdata/fakebin)
+	fakeEnvs map[string]string
+}
+
+func newHarness(t *testing.T, project map[string]string) *harness {
+	if testing.Short() || runtime.GOOS == "windows" {
+		t.Skip("e2e tests require a POSIX shell and are skipped in short mode")
+	}
+
+	h := &harness{
+		t:         t,
+		workDir:   t.TempDir(),
+		deployDir: t.TempDir(),
+		envmanDir: t.TempDir(),
+		cliLog:    filepath.Join(t.TempDir(), "cli.log"),
+		fakeEnvs:  map[string]string{},
+	}
+	for name, content := range project {
+		h.writeFile(name, content)
+	}
+
+	h.inputs = map[string]string{
+		"platform":            "ios,android",
+		"integration":         "auto",
+		"configuration":       "release",
+		"target":              "device",
+		"build_config":        "",
+		"options":             "",
+		"run_ionic_prepare":   "true",
+		"parallel_builds":     "false",
+		"continue_on_failure": "false",
+		"workdir":             h.workDir,
+		"android_app_type":    "apk",
+		"cache_local_deps":    "false",
+
+		"android_policy_check": "off",
+	}
+	return h
+}
+
+func (h *harness) writeFile(name, content string) {
+	pth := filepath.Join(h.workDir, name)
+	require.NoError(h.t, os.MkdirAll(filepath.Dir(pth), 0755))
+	require.NoError(h.t, os.WriteFile(pth, []byte(content), 0600))
+}
+
+// run runs the compiled step and returns its output
+func (h *harness) run() (string, error) {
+	fakeBin, err := filepath.Abs(filepath.Join("testdata", "fakebin"))
+	require.NoError(h.t, err)
+
+	envs := []string{
+		"PATH=" + fakeBin + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+		"BITRISE_DEPLOY_DIR=" + h.deployDir,
+		"FAKE_CLI_LOG=" + h.cliLog,
+		"FAKE_ENVMAN_DIR=" + h.envmanDir,
+	}
+	for key, value := range h.inputs {
+		envs = append(envs, key+"="+value)
+	}
+	for key, value := range h.fakeEnvs {
+		envs = append(envs, key+"="+value)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(stepPath)
+	cmd.Env = envs
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	return out.String(), err
+}
+
+// invocations returns the fake CLI invocations in order
+func (h *harness) invocations() []string {
+	content, err := os.ReadFile(h.cliLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(h.t, err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+// exportedEnvs returns the envs exported with envman
+func (h *harness) exportedEnvs() map[string]string {
+	entries, err := os.ReadDir(h.envmanDir)
+	require.NoError(h.t, err)
+
+	envs := map[string]string{}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(h.envmanDir, entry.Name()))
+		require.NoError(h.t, err)
+		envs[entry.Name()] = string(content)
+	}
+	return envs
+}
+
+// clean removes the outputs of the previous build, like the fresh checkout of a CI build
+func (h *harness) clean() {
+	require.NoError(h.t, os.RemoveAll(filepath.Join(h.workDir, "platforms")))
+}
+
+func (h *harness) deployed(name string) string {
+	return filepath.Join(h.deployDir, name)
+}
+
+func Test_CordovaDeviceBuild(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["android_app_type"] = "aab"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+
+	require.Equal(t, []string{
+		"cordova -v",
+		"ionic -v",
+		"ionic cordova prepare --no-build",
+		"ionic cordova build --release --device android -- -- --packageType=bundle",
+		"ionic cordova build --release --device ios",
+	}, h.invocations())
+
+	envs := h.exportedEnvs()
+	require.Equal(t, h.deployed("HelloCordova.ipa"), envs["BITRISE_IPA_PATH"])
+	require.Equal(t, h.deployed("HelloCordova.ipa"), envs["BITRISE_IPA_PATH_LIST"])
+	require.NotContains(t, envs, "BITRISE_APK_PATH_LIST")
+	require.Equal(t, h.deployed("app-release.aab"), envs["BITRISE_AAB_PATH"])
+	require.Equal(t, h.deployed("app-release.aab"), envs["BITRISE_AAB_PATH_LIST"])
+	require.Equal(t, "io.ionic.starter", envs["BITRISE_ANDROID_PACKAGE_NAME"])
+	require.Equal(t, "10203", envs["BITRISE_ANDROID_VERSION_CODE"])
+	require.NotContains(t, envs, "BITRISE_ANDROID_VARIANT")
+	require.Equal(t, "io.ionic.starter", envs["BITRISE_IOS_BUNDLE_ID"])
+	require.Equal(t, "1.2.3", envs["BITRISE_IOS_VERSION"])
+	require.Equal(t, "10203", envs["BITRISE_IOS_BUILD_NUMBER"])
+	require.Equal(t, "ABCDE12345", envs["BITRISE_IOS_TEAM_ID"])
+	require.Equal(t, "app-store", envs["BITRISE_IOS_EXPORT_METHOD"])
+	require.Equal(t, "2036-01-02T03:04:05Z", envs["BITRISE_IOS_PROFILE_EXPIRY"])
+	require.Contains(t, envs["BITRISE_IOS_ENTITLEMENTS"], `"application-identifier":"ABCDE12345.io.ionic.starter"`)
+	require.FileExists(t, envs["BITRISE_IPA_PATH"])
+	require.FileExists(t, envs["BITRISE_AAB_PATH"])
+
+	require.Equal(t, h.deployed("ionic-archive-manifest.json"), envs["BITRISE_IONIC_ARTIFACT_MANIFEST_PATH"])
+	var manifest struct {
+		Artifacts []struct {
+			Platform     string `json:"platform"`
+			Type         string `json:"type"`
+			SourcePath   string `json:"source_path"`
+			DeployedPath string `json:"deployed_path"`
+			Size         int64  `json:"size"`
+			SHA256       string `json:"sha256"`
+
+			AndroidManifest struct {
+				PackageName string `json:"package_name"`
+				TargetSDK   int    `json:"target_sdk"`
+			} `json:"android_manifest"`
+			IOSApp struct {
+				BundleID string `json:"bundle_id"`
+				Profile  struct {
+					ExportMethod string `json:"export_method"`
+				} `json:"provisioning_profile"`
+			} `json:"ios_app"`
+		} `json:"artifacts"`
+	}
+	content, err := os.ReadFile(envs["BITRISE_IONIC_ARTIFACT_MANIFEST_PATH"])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, &manifest))
+	require.Len(t, manifest.Artifacts, 2)
+	require.Equal(t, "ipa", manifest.Artifacts[0].Type)
+	require.Equal(t, filepath.Join(h.workDir, "platforms/ios/build/Release-iphoneos/HelloCordova.ipa"), manifest.Artifacts[0].SourcePath)
+	require.Equal(t, h.deployed("HelloCordova.ipa"), manifest.Artifacts[0].DeployedPath)
+	ipaInfo, err := os.Stat("testdata/fixtures/app.ipa")
+	require.NoError(t, err)
+	require.Equal(t, ipaInfo.Size(), manifest.Artifacts[0].Size)
+	require.Equal(t, "io.ionic.starter", manifest.Artifacts[0].IOSApp.BundleID)
+	require.Equal(t, "app-store", manifest.Artifacts[0].IOSApp.Profile.ExportMethod)
+	require.Equal(t, "aab", manifest.Artifacts[1].Type)
+	require.Equal(t, "android", manifest.Artifacts[1].Platform)
+	require.Equal(t, "io.ionic.starter", manifest.Artifacts[1].AndroidManifest.PackageName)
+	require.Equal(t, 33, manifest.Artifacts[1].AndroidManifest.TargetSDK)
+	require.Len(t, manifest.Artifacts[1].SHA256, 64)
+
+	require.Equal(t, h.deployed("SHA256SUMS"), envs["BITRISE_IONIC_CHECKSUMS_PATH"])
+	sums, err := os.ReadFile(h.deployed("SHA256SUMS"))
+	require.NoError(t, err)
+	require.Equal(t, manifest.Artifacts[0].SHA256+"  HelloCordova.ipa\n"+manifest.Artifacts[1].SHA256+"  app-release.aab\n", string(sums))
+	sidecar, err := os.ReadFile(h.deployed("app-release.aab.sha256"))
+	require.NoError(t, err)
+	require.Equal(t, manifest.Artifacts[1].SHA256+"  app-release.aab\n", string(sidecar))
+}
+
+func Test_CordovaEmulatorBuild(t *testing.T) {
+	if _, err := os.Stat("/usr/bin/zip"); err != nil {
+		t.Skip("zipping the .app and .dSYM requires /usr/bin/zip")
+	}
+
+	h := newHarness(t, cordovaProject)
+	h.inputs["configuration"] = "debug"
+	h.inputs["target"] = "emulator"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+
+	envs := h.exportedEnvs()
+	require.Equal(t, h.deployed("HelloCordova.app"), envs["BITRISE_APP_DIR_PATH"])
+	require.Equal(t, h.deployed("HelloCordova.app.zip"), envs["BITRISE_APP_PATH"])
+	require.Equal(t, h.deployed("HelloCordova.app.dSYM"), envs["BITRISE_DSYM_DIR_PATH"])
+	require.Equal(t, h.deployed("HelloCordova.app.dSYM.zip"), envs["BITRISE_DSYM_PATH"])
+	require.Equal(t, h.deployed("HelloCordova.app.dSYM.zip"), envs["BITRISE_DSYM_PATH_LIST"])
+	require.Equal(t, h.deployed("HelloCordova.app.zip"), envs["BITRISE_APP_PATH_LIST"])
+	require.Equal(t, h.deployed("app-debug.apk"), envs["BITRISE_APK_PATH"])
+	require.Equal(t, h.deployed("app-debug.apk"), envs["BITRISE_APK_PATH_LIST"])
+	require.Equal(t, "debug", envs["BITRISE_ANDROID_VARIANT"])
+	require.Equal(t, "10203", envs["BITRISE_ANDROID_VERSION_CODE"])
+	require.Equal(t, "1.2.3", envs["BITRISE_ANDROID_VERSION_NAME"])
+	require.Equal(t, "io.ionic.starter", envs["BITRISE_ANDROID_PACKAGE_NAME"])
+	require.Equal(t, "22", envs["BITRISE_ANDROID_MIN_SDK_VERSION"])
+	require.Equal(t, "33", envs["BITRISE_ANDROID_TARGET_SDK_VERSION"])
+	require.NoFileExists(t, h.deployed("app-debug-unsigned.apk"))
+	require.DirExists(t, envs["BITRISE_APP_DIR_PATH"])
+	require.FileExists(t, envs["BITRISE_APP_PATH"])
+	require.FileExists(t, envs["BITRISE_DSYM_PATH"])
+	require.FileExists(t, envs["BITRISE_APK_PATH"])
+
+	// the artifacts of the previous run in the deploy dir are replaced
+	h.clean()
+	out, err = h.run()
+	require.NoError(t, err, out)
+	// a failed .app export is only a warning
+	require.NotContains(t, out, "Failed to export apps")
+	require.DirExists(t, h.deployed("HelloCordova.app"))
+	require.NoDirExists(t, h.deployed("HelloCordova.app/HelloCordova.app"))
+}
+
+func Test_AABFallbackWithOldCordova(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.inputs["android_app_type"] = "aab"
+	h.fakeEnvs["FAKE_CORDOVA_VERSION"] = "8.0.0"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+	require.Contains(t, out, "Cordova doesn't support exporting aab, falling back to apk")
+
+	envs := h.exportedEnvs()
+	require.Equal(t, h.deployed("app-release.apk"), envs["BITRISE_APK_PATH"])
+	require.NotContains(t, envs, "BITRISE_AAB_PATH")
+}
+
+func Test_DependencyInstallWithYarn(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.writeFile("yarn.lock", "")
+	h.inputs["platform"] = "android"
+	h.inputs["ionic_version"] = "latest"
+	h.inputs["cordova_version"] = "12.0.0"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+
+	require.Equal(t, []string{
+		"yarn remove cordova",
+		"yarn global add cordova@12.0.0",
+		"yarn remove @ionic/cli",
+		"yarn global remove ionic",
+		"yarn global add @ionic/cli@latest",
+		"cordova -v",
+		"ionic -v",
+		"ionic cordova prepare --no-build",
+		"ionic cordova build --release --device android -- -- --packageType=apk",
+	}, h.invocations())
+}
+
+func Test_BuildFailure(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.fakeEnvs["FAKE_FAIL_PLATFORM"] = "ios"
+
+	out, err := h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "fake ionic: ios build failed")
+	require.NotContains(t, h.exportedEnvs(), "BITRISE_IPA_PATH")
+}
+
+func Test_StaleOutputs(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+
+	// a deterministic build rewrites the apk with the same content
+	require.NoError(t, os.RemoveAll(h.envmanDir))
+	require.NoError(t, os.MkdirAll(h.envmanDir, 0755))
+	out, err = h.run()
+	require.NoError(t, err, out)
+	require.NotContains(t, out, "The build did not write any apk output")
+	require.Equal(t, h.deployed("app-release.apk"), h.exportedEnvs()["BITRISE_APK_PATH"])
+
+	// an up-to-date build does not rewrite the apk of the previous build (like a restored cache)
+	require.NoError(t, os.RemoveAll(h.envmanDir))
+	require.NoError(t, os.MkdirAll(h.envmanDir, 0755))
+	h.fakeEnvs["FAKE_UP_TO_DATE"] = "true"
+	out, err = h.run()
+	require.NoError(t, err, out)
+	require.Contains(t, out, "The build did not write any apk output, using the existing ones, which may come from an earlier build:")
+	require.Equal(t, h.deployed("app-release.apk"), h.exportedEnvs()["BITRISE_APK_PATH"])
+}
+
+func Test_WebOnlyProject(t *testing.T) {
+	h := newHarness(t, map[string]string{
+		"ionic.config.json": `{"name": "HelloWeb", "type": "react"}`,
+		"package.json":      `{"name": "hello-web"}`,
+	})
+
+	out, err := h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "the project has no native integration")
+	require.Empty(t, h.invocations())
+}
+
+func Test_ArtifactNameTemplate(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["artifact_name_template"] = "{appId}-{version}-{flavor}-{abi}.{ext}"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+
+	envs := h.exportedEnvs()
+	require.Equal(t, h.deployed("io.ionic.starter-1.0.0.ipa"), envs["BITRISE_IPA_PATH"])
+	require.Equal(t, h.deployed("io.ionic.starter-1.0.0.apk"), envs["BITRISE_APK_PATH"])
+	require.FileExists(t, envs["BITRISE_IPA_PATH"])
+	require.FileExists(t, envs["BITRISE_APK_PATH"])
+}
+
+func Test_AndroidPolicyCheck(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.inputs["android_policy_check"] = "fail"
+	h.inputs["android_min_target_sdk"] = "34"
+
+	out, err := h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "Android policy check failed: app-release.apk: targetSdkVersion 33 is below the required 34; app-release.apk: android:debuggable is true in a release build")
+	// the artifacts are exported before the check fails the step
+	require.Equal(t, h.deployed("app-release.apk"), h.exportedEnvs()["BITRISE_APK_PATH"])
+
+	h.inputs["android_policy_check"] = "warn"
+	h.clean()
+	out, err = h.run()
+	require.NoError(t, err, out)
+	require.Contains(t, out, "Android policy violation: app-release.apk: targetSdkVersion 33 is below the required 34")
+}
+
+func Test_SizeBudget(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.inputs["max_apk_size"] = "100 B"
+
+	out, err := h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "Size budget exceeded:")
+	require.Contains(t, out, "app-release.apk is 505 B, which exceeds the apk size budget of 100 B:")
+	require.Regexp(t, `other\s+\d+ B \(1 files`, out)
+	require.Equal(t, h.deployed("app-release.apk"), h.exportedEnvs()["BITRISE_APK_PATH"])
+	// no command failed the step, so there is nothing to diagnose
+	require.NotContains(t, out, "Diagnosing the failure")
+	require.NotContains(t, h.exportedEnvs(), "BITRISE_IONIC_FAILURE_CATEGORY")
+
+	h.inputs["max_apk_size"] = "1 MB"
+	h.clean()
+	out, err = h.run()
+	require.NoError(t, err, out)
+}
+
+func Test_ArtifactDiff(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+
+	// the previous build had an older version, which requested the camera permission
+	content, err := os.ReadFile(h.deployed("ionic-archive-manifest.json"))
+	require.NoError(t, err)
+	previous := strings.Replace(string(content), `"version_code": 10203`, `"version_code": 10202, "permissions": ["android.permission.CAMERA"]`, -1)
+	previousPth := filepath.Join(t.TempDir(), "ionic-archive-manifest.json")
+	require.NoError(t, os.WriteFile(previousPth, []byte(previous), 0644))
+
+	h.inputs["previous_artifacts"] = previousPth
+	h.clean()
+	out, err = h.run()
+	require.NoError(t, err, out)
+	require.Equal(t, h.deployed("ionic-archive-diff.txt"), h.exportedEnvs()["BITRISE_IONIC_ARTIFACT_DIFF_PATH"])
+
+	diff, err := os.ReadFile(h.deployed("ionic-archive-diff.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "app-release.apk (previous: app-release.apk)\n"+
+		"  version code: 10202 -> 10203\n"+
+		"  permissions removed: android.permission.CAMERA\n", string(diff))
+
+	// a single artifact can be compared too
+	h.inputs["previous_artifacts"] = h.deployed("app-release.apk")
+	h.clean()
+	out, err = h.run()
+	require.NoError(t, err, out)
+	require.Contains(t, out, "app-release.apk (previous: app-release.apk)\n  no changes")
+}
+
+func Test_BuildProvenance(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.inputs["ionic_username"] = "user@example.com"
+	h.inputs["ionic_password"] = "s3cr3t"
+	// the work dir is not a git repository, so the commit of the git clone step is recorded
+	h.inputs["GIT_CLONE_COMMIT_HASH"] = "0123456789abcdef0123456789abcdef01234567"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+	require.Equal(t, h.deployed("ionic-archive-provenance.intoto.json"), h.exportedEnvs()["BITRISE_IONIC_PROVENANCE_PATH"])
+
+	content, err := os.ReadFile(h.deployed("ionic-archive-provenance.intoto.json"))
+	require.NoError(t, err)
+	var statement struct {
+		Subject []struct {
+			Name   string            `json:"name"`
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+		Predicate struct {
+			BuildDefinition struct {
+				ResolvedDependencies []struct {
+					Name   string            `json:"name"`
+					Digest map[string]string `json:"digest"`
+				} `json:"resolvedDependencies"`
+				InternalParameters struct {
+					IonicVersion   string   `json:"ionicVersion"`
+					CordovaVersion string   `json:"cordovaVersion"`
+					PackageManager string   `json:"packageManager"`
+					Commands       []string `json:"commands"`
+				} `json:"internalParameters"`
+			} `json:"buildDefinition"`
+		} `json:"predicate"`
+	}
+	require.NoError(t, json.Unmarshal(content, &statement))
+	require.Len(t, statement.Subject, 1)
+	require.Equal(t, "app-release.apk", statement.Subject[0].Name)
+	require.Len(t, statement.Subject[0].Digest["sha256"], 64)
+
+	internal := statement.Predicate.BuildDefinition.InternalParameters
+	require.Equal(t, "6.20.1", internal.IonicVersion)
+	require.Equal(t, "12.0.0", internal.CordovaVersion)
+	require.Equal(t, "npm", internal.PackageManager)
+	require.Contains(t, internal.Commands, `ionic "login" "***" "***"`)
+	require.NotContains(t, internal.Commands, `git "rev-parse" "HEAD"`)
+	require.NotContains(t, string(content), "s3cr3t")
+
+	dependencies := statement.Predicate.BuildDefinition.ResolvedDependencies
+	require.NotEmpty(t, dependencies)
+	require.Equal(t, "source", dependencies[0].Name)
+	require.Equal(t, "0123456789abcdef0123456789abcdef01234567", dependencies[0].Digest["gitCommit"])
+}
+
+func Test_Signing(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.inputs["signing_key"] = base64.StdEncoding.EncodeToString(privateKey.Seed())
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+	require.NotContains(t, out, h.inputs["signing_key"])
+	for _, name := range []string{"ionic-archive-manifest.json", "SHA256SUMS"} {
+		require.NoError(t, signing.Verify(publicKey, h.deployed(name), h.deployed(name+".sig")))
+	}
+
+	h.inputs["signing_key"] = "not a key"
+	out, err = h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "Invalid signing key, error: neither a PEM nor a base64 encoded key")
+}
+
+func Test_IonicAuthentication(t *testing.T) {
+	t.Run("token", func(t *testing.T) {
+		h := newHarness(t, cordovaProject)
+		h.inputs["platform"] = "android"
+		h.inputs["ionic_token"] = "ion_token"
+		h.inputs["ionic_username"] = "user@example.com"
+		h.inputs["ionic_password"] = "s3cr3t"
+
+		out, err := h.run()
+		require.NoError(t, err, out)
+		require.NotContains(t, out, "ion_token")
+		require.Equal(t, []string{
+			"ionic -v",
+			"ionic cordova prepare --no-build (IONIC_TOKEN=ion_token)",
+			"ionic cordova build --release --device android -- -- --packageType=apk (IONIC_TOKEN=ion_token)",
+		}, filterInvocations(h.invocations(), "ionic "))
+	})
+
+	t.Run("username and password, logged out after a failed build", func(t *testing.T) {
+		h := newHarness(t, cordovaProject)
+		h.inputs["ionic_username"] = "user@example.com"
+		h.inputs["ionic_password"] = "s3cr3t"
+		h.fakeEnvs["FAKE_FAIL_PLATFORM"] = "ios"
+
+		out, err := h.run()
+		require.Error(t, err)
+		require.Equal(t, []string{
+			"ionic -v",
+			"ionic login user@example.com s3cr3t",
+			"ionic cordova prepare --no-build",
+			"ionic cordova build --release --device android -- -- --packageType=apk",
+			"ionic cordova build --release --device ios",
+			"ionic logout",
+		}, filterInvocations(h.invocations(), "ionic "))
+		require.Contains(t, out, "ionic login *** ***")
+	})
+}
+
+// filterInvocations returns the invocations of a fake CLI
+func filterInvocations(invocations []string, prefix string) []string {
+	var filtered []string
+	for _, invocation := range invocations {
+		if strings.HasPrefix(invocation, prefix) {
+			filtered = append(filtered, invocation)
+		}
+	}
+	return filtered
+}
+
+func Test_SecretRedaction(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.writeFile("build.json", `{"android": {"release": {"keystore": "release.keystore", "storePassword": "json-store-s3cr3t", "alias": "key0", "password": "json-key-s3cr3t"}}}`)
+	h.inputs["platform"] = "android"
+	h.inputs["build_config"] = filepath.Join(h.workDir, "build.json")
+	h.inputs["options"] = "--storePassword=option-s3cr3t --password json-key-s3cr3t"
+	h.inputs["ionic_username"] = "user@example.com"
+	h.inputs["ionic_password"] = "ionic-s3cr3t"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+	for _, secret := range []string{"json-store-s3cr3t", "json-key-s3cr3t", "option-s3cr3t", "user@example.com", "ionic-s3cr3t"} {
+		require.NotContains(t, out, secret)
+	}
+	require.Contains(t, out, `"--storePassword=***" "--password" "***"`)
+	// the commands get the real values
+	require.Contains(t, h.invocations(), "ionic cordova build --release --device android --buildConfig "+filepath.Join(h.workDir, "build.json")+" --storePassword=option-s3cr3t --password json-key-s3cr3t -- -- --packageType=apk")
+}
+
+func Test_PlatformOptions(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["options"] = "--verbose"
+	h.inputs["ios_options"] = `-- --buildFlag="-UseModernBuildSystem=0"`
+	h.inputs["android_options"] = "-- -- --gradleArg=-PcdvMinSdkVersion=24"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+	require.Equal(t, []string{
+		"ionic cordova build --release --device android --verbose -- -- --gradleArg=-PcdvMinSdkVersion=24 --packageType=apk",
+		"ionic cordova build --release --device ios --verbose -- --buildFlag=-UseModernBuildSystem=0",
+	}, filterInvocations(h.invocations(), "ionic cordova build"))
+}
+
+func Test_ParallelBuilds(t *testing.T) {
+	t.Run("cordova", func(t *testing.T) {
+		h := newHarness(t, cordovaProject)
+		h.inputs["parallel_builds"] = "true"
+		h.inputs["options"] = "--prod"
+
+		out, err := h.run()
+		require.NoError(t, err, out)
+
+		invocations := filterInvocations(h.invocations(), "ionic cordova")
+		require.Equal(t, []string{"ionic cordova prepare android --prod", "ionic cordova prepare ios --no-build"}, invocations[:2])
+		require.ElementsMatch(t, []string{
+			"ionic cordova build --release --device android --prod --no-build -- -- --packageType=apk",
+			"ionic cordova build --release --device ios --prod --no-build",
+		}, invocations[2:])
+
+		envs := h.exportedEnvs()
+		require.FileExists(t, envs["BITRISE_IPA_PATH"])
+		require.FileExists(t, envs["BITRISE_APK_PATH"])
+		// the logs of the platforms are exported like the phase logs
+		for _, platform := range []string{"IOS", "ANDROID"} {
+			pth := envs["BITRISE_IONIC_"+platform+"_BUILD_LOG_PATH"]
+			require.FileExists(t, pth)
+			require.Regexp(t, `^ionic-`+strings.ToLower(platform)+`-build-\d{8}-\d{6}\.log$`, filepath.Base(pth))
+		}
+
+		// the lines of the concurrent builds are prefixed in the build phase log too
+		build, err := os.ReadFile(envs["BITRISE_IONIC_BUILD_LOG_PATH"])
+		require.NoError(t, err)
+		require.Contains(t, string(build), `[ios] $ ionic "cordova" "build" "--release" "--device" "ios"`)
+		require.Contains(t, string(build), `[android] $ ionic "cordova" "build" "--release" "--device" "android"`)
+	})
+
+	t.Run("without prepare", func(t *testing.T) {
+		h := newHarness(t, cordovaProject)
+		h.inputs["parallel_builds"] = "true"
+		h.inputs["run_ionic_prepare"] = "false"
+		h.inputs["ios_options"] = "--prod"
+
+		out, err := h.run()
+		require.NoError(t, err, out)
+		require.Contains(t, out, "The web assets are not built, as ionic prepare is disabled")
+		require.Contains(t, out, "The ios options of the web build (--prod) are ignored")
+		require.Empty(t, filterInvocations(h.invocations(), "ionic cordova prepare"))
+		require.Len(t, filterInvocations(h.invocations(), "ionic cordova build"), 2)
+	})
+
+	t.Run("a failed platform waits for the other", func(t *testing.T) {
+		h := newHarness(t, cordovaProject)
+		h.inputs["parallel_builds"] = "true"
+		h.fakeEnvs["FAKE_FAIL_PLATFORM"] = "ios"
+
+		out, err := h.run()
+		require.Error(t, err)
+		require.Contains(t, out, "[ios] fake ionic: ios build failed")
+		require.Contains(t, out, "1 of 2 platform builds failed, ios: command failed")
+		require.Len(t, filterInvocations(h.invocations(), "ionic cordova build"), 2)
+
+		content, err := os.ReadFile(h.exportedEnvs()["BITRISE_IONIC_IOS_BUILD_LOG_PATH"])
+		require.NoError(t, err)
+		require.Equal(t, "fake ionic: ios build failed\n", string(content))
+	})
+}
+
+func Test_ContinueOnFailure(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["continue_on_failure"] = "true"
+	h.fakeEnvs["FAKE_FAIL_PLATFORM"] = "android"
+
+	out, err := h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "1 of 2 platform builds failed, android: command failed")
+	require.Len(t, filterInvocations(h.invocations(), "ionic cordova build"), 2)
+
+	envs := h.exportedEnvs()
+	require.Equal(t, "failed", envs["BITRISE_IONIC_ANDROID_STATUS"])
+	require.Equal(t, "succeeded", envs["BITRISE_IONIC_IOS_STATUS"])
+	require.FileExists(t, envs["BITRISE_IPA_PATH"])
+	require.NotContains(t, envs, "BITRISE_APK_PATH")
+	require.FileExists(t, envs["BITRISE_IONIC_ARTIFACT_MANIFEST_PATH"])
+}
+
+func Test_FailureDiagnosis(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.fakeEnvs["FAKE_FAIL_PLATFORM"] = "android"
+	h.fakeEnvs["FAKE_FAIL_OUTPUT"] = "Current working directory is not a Cordova-based project."
+
+	out, err := h.run()
+	require.Error(t, err)
+	require.Contains(t, out, "Hint (not-cordova-project):")
+	require.Equal(t, "not-cordova-project", h.exportedEnvs()["BITRISE_IONIC_FAILURE_CATEGORY"])
+}
+
+func Test_PhaseLogs(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "android"
+	h.inputs["ionic_username"] = "user@example.com"
+	h.inputs["ionic_password"] = "s3cr3t"
+	h.fakeEnvs["FAKE_FAIL_PLATFORM"] = "android"
+	h.fakeEnvs["FAKE_FAIL_OUTPUT"] = "Unsupported class file major version 65"
+
+	// the logs are exported even if the build fails
+	out, err := h.run()
+	require.Error(t, err)
+
+	envs := h.exportedEnvs()
+	for _, key := range []string{"BITRISE_IONIC_INSTALL_LOG_PATH", "BITRISE_IONIC_LOGIN_LOG_PATH", "BITRISE_IONIC_PREPARE_LOG_PATH", "BITRISE_IONIC_BUILD_LOG_PATH"} {
+		require.Equal(t, h.deployDir, filepath.Dir(envs[key]), out)
+	}
+
+	login, err := os.ReadFile(envs["BITRISE_IONIC_LOGIN_LOG_PATH"])
+	require.NoError(t, err)
+	require.Contains(t, string(login), `$ ionic "login" "***" "***"`)
+	require.NotContains(t, string(login), "s3cr3t")
+	require.Contains(t, string(login), `$ ionic "logout"`)
+
+	build, err := os.ReadFile(envs["BITRISE_IONIC_BUILD_LOG_PATH"])
+	require.NoError(t, err)
+	require.Contains(t, string(build), "$ ionic \"cordova\" \"build\"")
+	require.Contains(t, string(build), "Unsupported class file major version 65\nfake ionic: android build failed\n")
+}
+
+func Test_Timings(t *testing.T) {
+	h := newHarness(t, cordovaProject)
+	h.inputs["platform"] = "ios,android"
+	h.inputs["parallel_builds"] = "true"
+
+	out, err := h.run()
+	require.NoError(t, err, out)
+	require.Contains(t, out, "Phase timings")
+
+	pth := h.exportedEnvs()["BITRISE_IONIC_TIMINGS_PATH"]
+	require.Equal(t, filepath.Join(h.deployDir, "ionic-archive-timings.json"), pth, out)
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+
+	var timings struct {
+		Phases []struct {
+			Name string `json:"name"`
+		} `json:"phases"`
+	}
+	require.NoError(t, json.Unmarshal(content, &timings))
+	var names []string
+	for _, phase := range timings.Phases {
+		names = append(names, phase.Name)
+	}
+	require.Subset(t, names, []string{"version detection", "web build", "build android", "build ios", "artifact collection"})
+}
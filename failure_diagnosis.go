@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/diagnosis"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const failureCategoryEnvKey = "BITRISE_IONIC_FAILURE_CATEGORY"
+
+// commandFailures returns the failed commands, which failed the step: the failed platform builds
+// or the command, which is wrapped into the step's error.
+// The failed commands, which are ignored by the step, are not returned.
+func commandFailures(err error) []*diagnosis.CommandError {
+	var buildFailure *platformBuildFailure
+	if errors.As(err, &buildFailure) {
+		var failures []*diagnosis.CommandError
+		for _, platform := range buildFailure.platforms {
+			var commandErr *diagnosis.CommandError
+			if errors.As(buildFailure.errs[platform], &commandErr) {
+				failures = append(failures, commandErr)
+			}
+		}
+		return failures
+	}
+
+	var commandErr *diagnosis.CommandError
+	if errors.As(err, &commandErr) {
+		return []*diagnosis.CommandError{commandErr}
+	}
+	return nil
+}
+
+// diagnoseFailures prints a hint for the known failures in the output of the failed commands,
+// and returns the failure category of the last failed command.
+// It returns an empty category if no command failed.
+func diagnoseFailures(failures []*diagnosis.CommandError) string {
+	if len(failures) == 0 {
+		return ""
+	}
+
+	fmt.Println()
+	log.Infof("Diagnosing the failure")
+
+	category := diagnosis.CategoryUnknown
+	hinted := map[string]bool{}
+	for _, failure := range failures {
+		found, ok := diagnosis.Diagnose(diagnosis.Catalog, failure.Output)
+		if !ok {
+			category = diagnosis.CategoryUnknown
+			continue
+		}
+		category = found.Category
+
+		if hinted[found.Category] {
+			continue
+		}
+		hinted[found.Category] = true
+		log.Warnf("$ %s failed: %s", failure.Command, found.Line)
+		log.Printf("Hint (%s): %s", found.Category, found.Hint)
+	}
+	if len(hinted) == 0 {
+		log.Printf("The failure doesn't match any known failure, check the output of the failed command: %s", failures[len(failures)-1].Command)
+	}
+	return category
+}
+
+// exportFailureCategory diagnoses the failed commands, which failed the step with the error, and exports the failure category.
+// Nothing is exported if the step failed for an other reason than a failed command.
+func exportFailureCategory(r runner.Runner, err error) {
+	category := diagnoseFailures(commandFailures(err))
+	if category == "" {
+		return
+	}
+	if err := exportEnvironment(r, failureCategoryEnvKey, category); err != nil {
+		log.Warnf("Failed to export the failure category, error: %s", err)
+		return
+	}
+	log.Donef("The failure category is now available in the Environment Variable: %s (value: %s)", failureCategoryEnvKey, category)
+}
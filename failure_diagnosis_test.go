@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/diagnosis"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_commandFailures(t *testing.T) {
+	signingFailure := &diagnosis.CommandError{Command: "ionic cordova build ios", Output: "error: No signing certificate \"iOS Distribution\" found\n", Err: errors.New("exit status 65")}
+	unknownFailure := &diagnosis.CommandError{Command: "ionic cordova build android", Output: "BUILD FAILED\n", Err: errors.New("exit status 1")}
+
+	tests := []struct {
+		name string
+		err  error
+		want []*diagnosis.CommandError
+	}{
+		{name: "not a command failure", err: errors.New("No apk generated"), want: nil},
+		{name: "wrapped command failure", err: fmt.Errorf("ionic login command failed, error: %w", unknownFailure), want: []*diagnosis.CommandError{unknownFailure}},
+		{
+			name: "platform build failures",
+			err: &platformBuildFailure{
+				platforms: []string{"android", "ios"},
+				statuses:  map[string]string{"android": platformStatusFailed, "ios": platformStatusFailed},
+				errs: map[string]error{
+					"android": fmt.Errorf("command failed, error: %w", unknownFailure),
+					"ios":     fmt.Errorf("command failed, error: %w", signingFailure),
+				},
+			},
+			want: []*diagnosis.CommandError{unknownFailure, signingFailure},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, commandFailures(tt.err))
+		})
+	}
+}
+
+func Test_diagnoseFailures(t *testing.T) {
+	signingFailure := &diagnosis.CommandError{Command: "ionic cordova build ios", Output: "error: No signing certificate \"iOS Distribution\" found\n"}
+	unknownFailure := &diagnosis.CommandError{Command: "ionic cordova build android", Output: "BUILD FAILED\n"}
+
+	tests := []struct {
+		name     string
+		failures []*diagnosis.CommandError
+		want     string
+	}{
+		{name: "no failed command", failures: nil, want: ""},
+		{name: "known failure", failures: []*diagnosis.CommandError{signingFailure}, want: "ios-code-signing-identity"},
+		{name: "unknown failure", failures: []*diagnosis.CommandError{unknownFailure}, want: "unknown"},
+		{name: "the category of the last failure", failures: []*diagnosis.CommandError{unknownFailure, signingFailure}, want: "ios-code-signing-identity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, diagnoseFailures(tt.failures))
+		})
+	}
+}
+
+func Test_exportFailureCategory(t *testing.T) {
+	r := runner.NewFake(nil)
+	commandErr := &diagnosis.CommandError{Command: "ionic cordova build android", Output: "Error [ERR_REQUIRE_ESM]: require() of ES Module\n", Err: errors.New("exit status 1")}
+	exportFailureCategory(r, fmt.Errorf("command failed, error: %w", commandErr))
+	require.Equal(t, map[string]string{"BITRISE_IONIC_FAILURE_CATEGORY": "node-esm-require"}, exportedEnvs(r.Records()))
+
+	// the step failed for an other reason than a failed command
+	r = runner.NewFake(nil)
+	exportFailureCategory(r, errors.New("Size budget exceeded"))
+	require.Empty(t, exportedEnvs(r.Records()))
+}
@@ -7,16 +7,16 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/bitrise-io/go-utils/command"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
 	ver "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
 )
 
 // Version returns ionic version
-func Version() (*ver.Version, error) {
-	cmd := command.New("ionic", "-v")
+func Version(r runner.Runner) (*ver.Version, error) {
+	cmd := runner.New("ionic", "-v")
 	cmd.SetStdin(strings.NewReader("Y"))
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+	out, err := r.RunAndReturnTrimmedCombinedOutput(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -45,9 +45,9 @@ func Version() (*ver.Version, error) {
 }
 
 // CordovaVersion returns cordova version
-func CordovaVersion() (*ver.Version, error) {
-	cmd := command.New("cordova", "-v")
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
+func CordovaVersion(r runner.Runner) (*ver.Version, error) {
+	cmd := runner.New("cordova", "-v")
+	out, err := r.RunAndReturnTrimmedCombinedOutput(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -65,20 +65,34 @@ func CordovaVersion() (*ver.Version, error) {
 	return version, nil
 }
 
+// TokenEnvKey is the environment variable, which authenticates the ionic commands with a personal access token without logging in
+const TokenEnvKey = "IONIC_TOKEN"
+
 // LoginCommand returns ionic login comand model
-func LoginCommand(username string, password string) *command.Model {
+func LoginCommand(username string, password string) *runner.Command {
 	cmdArgs := []string{"ionic", "login", username, password}
-	return command.New(cmdArgs[0], cmdArgs[1:]...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...)
+}
+
+// LogoutCommand returns ionic logout command model, which removes the session stored by ionic login
+func LogoutCommand() *runner.Command {
+	return runner.New("ionic", "logout")
 }
 
 // PrepareCommand returns ionic cordova prepare command model
-func PrepareCommand(ionicMajorVersion int) *command.Model {
+func PrepareCommand(ionicMajorVersion int) *runner.Command {
 	cmdArgs := []string{"ionic"}
 	if ionicMajorVersion > 2 {
 		cmdArgs = append(cmdArgs, "cordova")
 	}
 	cmdArgs = append(cmdArgs, "prepare", "--no-build")
-	return command.New(cmdArgs[0], cmdArgs[1:]...)
+	return runner.New(cmdArgs[0], cmdArgs[1:]...)
+}
+
+// PlatformPrepareCommand returns ionic cordova prepare command model of the platform, which also builds the web assets, unless the options contain --no-build (for ionic 4 and later).
+// It is used before building the platforms concurrently with ionic cordova build --no-build, so that they don't build the shared web assets at the same time.
+func PlatformPrepareCommand(platform string, options []string) *runner.Command {
+	return runner.New("ionic", append([]string{"cordova", "prepare", platform}, options...)...)
 }
 
 // PackageNameFromVersion returns either "ionic" or "@ionic/cli" based on the required version
@@ -1,8 +1,10 @@
 package ionic
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
 	"github.com/stretchr/testify/require"
 )
 
@@ -94,3 +96,49 @@ func Test_PackageNameFromVersion(t *testing.T) {
 		})
 	}
 }
+
+func Test_Version(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", out: "6.20.1", want: "6.20.1"},
+		{name: "interactive output", out: "\x1b[1000D\x1b[K3.2.0", want: "3.2.0"},
+		{name: "update notice", out: "6.20.1\n\n   ╭──────────────╮\n   │ Update available: 7.1.1 │", want: "6.20.1"},
+		{name: "no version", out: "command not found", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+				return tt.out, nil
+			})
+
+			got, err := Version(r)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, []string{"ionic -v"}, r.CommandStrings())
+		})
+	}
+}
+
+func Test_CordovaVersion(t *testing.T) {
+	r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		return "12.0.0", nil
+	})
+
+	got, err := CordovaVersion(r)
+	require.NoError(t, err)
+	require.Equal(t, "12.0.0", got.String())
+
+	r = runner.NewFake(func(cmd *runner.Command) (string, error) {
+		return "", errors.New("exec: \"cordova\": executable file not found in $PATH")
+	})
+	_, err = CordovaVersion(r)
+	require.Error(t, err)
+}
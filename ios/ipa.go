@@ -0,0 +1,93 @@
+package ios
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// IPA is the metadata of an app archive read from its Info.plist and embedded.mobileprovision
+type IPA struct {
+	BundleID    string
+	Version     string
+	BuildNumber string
+	// Profile is nil if the app has no embedded provisioning profile
+	Profile *ProvisioningProfile
+}
+
+// InspectIPA reads the Info.plist and the embedded.mobileprovision of the main app (Payload/<name>.app) of the IPA
+func InspectIPA(pth string) (IPA, error) {
+	r, err := zip.OpenReader(pth)
+	if err != nil {
+		return IPA{}, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	files := map[string]*zip.File{}
+	appDir := ""
+	for _, f := range r.File {
+		files[f.Name] = f
+		// the app extensions and the watch app are nested in the main app, they are not directly under Payload
+		if dir, file := path.Split(f.Name); file == "Info.plist" && isPayloadApp(strings.TrimSuffix(dir, "/")) {
+			appDir = strings.TrimSuffix(dir, "/")
+		}
+	}
+	if appDir == "" {
+		return IPA{}, fmt.Errorf("no Payload/*.app/Info.plist found in %s", pth)
+	}
+
+	data, err := readZipFile(files[appDir+"/Info.plist"])
+	if err != nil {
+		return IPA{}, err
+	}
+	value, err := ParsePlist(data)
+	if err != nil {
+		return IPA{}, fmt.Errorf("failed to parse the Info.plist of %s: %s", pth, err)
+	}
+	info, ok := value.(map[string]interface{})
+	if !ok {
+		return IPA{}, errors.New("the Info.plist is not a dict")
+	}
+
+	ipa := IPA{
+		BundleID:    stringValue(info, "CFBundleIdentifier"),
+		Version:     stringValue(info, "CFBundleShortVersionString"),
+		BuildNumber: stringValue(info, "CFBundleVersion"),
+	}
+
+	if f, ok := files[appDir+"/embedded.mobileprovision"]; ok {
+		data, err := readZipFile(f)
+		if err != nil {
+			return IPA{}, err
+		}
+		profile, err := ParseProvisioningProfile(data)
+		if err != nil {
+			return IPA{}, fmt.Errorf("failed to parse the embedded.mobileprovision of %s: %s", pth, err)
+		}
+		ipa.Profile = &profile
+	}
+
+	return ipa, nil
+}
+
+// isPayloadApp tells whether the directory is a Payload/<name>.app
+func isPayloadApp(dir string) bool {
+	parts := strings.Split(dir, "/")
+	return len(parts) == 2 && parts[0] == "Payload" && strings.HasSuffix(parts[1], ".app")
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rc.Close()
+	}()
+	return io.ReadAll(rc)
+}
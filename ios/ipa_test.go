@@ -0,0 +1,208 @@
+package ios
+
+import (
+	"archive/zip"
+	"encoding/asn1"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const profilePlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>Ionic Starter Ad Hoc</string>
+	<key>UUID</key>
+	<string>5b8d3c0e-1f2a-4c3b-9d4e-6f7a8b9c0d1e</string>
+	<key>TeamIdentifier</key>
+	<array>
+		<string>ABCDE12345</string>
+	</array>
+	<key>TeamName</key>
+	<string>Ionic Team</string>
+	<key>ExpirationDate</key>
+	<date>2030-01-02T03:04:05Z</date>
+	<key>ProvisionedDevices</key>
+	<array>
+		<string>00008030-001A2B3C4D5E6F70</string>
+	</array>
+	<key>Entitlements</key>
+	<dict>
+		<key>application-identifier</key>
+		<string>ABCDE12345.io.ionic.starter</string>
+		<key>get-task-allow</key>
+		<false/>
+		<key>aps-environment</key>
+		<string>production</string>
+	</dict>
+</dict>
+</plist>
+`
+
+var wantProfile = ProvisioningProfile{
+	Name:               "Ionic Starter Ad Hoc",
+	UUID:               "5b8d3c0e-1f2a-4c3b-9d4e-6f7a8b9c0d1e",
+	TeamID:             "ABCDE12345",
+	TeamName:           "Ionic Team",
+	ExpirationDate:     time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC),
+	ProvisionedDevices: []string{"00008030-001A2B3C4D5E6F70"},
+	Entitlements: map[string]interface{}{
+		"application-identifier": "ABCDE12345.io.ionic.starter",
+		"get-task-allow":         false,
+		"aps-environment":        "production",
+	},
+}
+
+// buildSignedData wraps the content into a DER encoded CMS SignedData message without certificates and signers
+func buildSignedData(t *testing.T, content []byte) []byte {
+	type encapContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     []byte `asn1:"explicit,tag:0"`
+	}
+	signedData, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms []asn1.ObjectIdentifier `asn1:"set"`
+		EncapContentInfo encapContentInfo
+		SignerInfos      []asn1.RawValue `asn1:"set"`
+	}{
+		Version:          1,
+		DigestAlgorithms: []asn1.ObjectIdentifier{{2, 16, 840, 1, 101, 3, 4, 2, 1}},
+		EncapContentInfo: encapContentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, Content: content},
+	})
+	require.NoError(t, err)
+
+	message, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+	require.NoError(t, err)
+	return message
+}
+
+func writeIPA(t *testing.T, pth string, files map[string][]byte) {
+	f, err := os.Create(pth)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		entryWriter, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = entryWriter.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+}
+
+func Test_InspectIPA(t *testing.T) {
+	extensionInfoPlist := buildBinaryPlist(map[string]interface{}{"CFBundleIdentifier": "io.ionic.starter.widget"})
+
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		want    IPA
+		wantErr bool
+	}{
+		{
+			name: "binary Info.plist with a signed profile",
+			files: map[string][]byte{
+				"Payload/App.app/PlugIns/Widget.appex/Info.plist": extensionInfoPlist,
+				"Payload/App.app/Info.plist":                      buildBinaryPlist(infoPlistValue),
+				"Payload/App.app/embedded.mobileprovision":        buildSignedData(t, []byte(profilePlist)),
+			},
+			want: IPA{BundleID: "io.ionic.starter", Version: "1.2.3", BuildNumber: "10203", Profile: &wantProfile},
+		},
+		{
+			name: "XML Info.plist with a BER encoded profile",
+			files: map[string][]byte{
+				"Payload/App.app/Info.plist": []byte(xmlInfoPlist),
+				// indefinite lengths are not supported by encoding/asn1, the plist is located in the message instead
+				"Payload/App.app/embedded.mobileprovision": append([]byte{0x30, 0x80, 0x06, 0x09}, []byte(profilePlist)...),
+			},
+			want: IPA{BundleID: "io.ionic.starter", Version: "1.2.3", BuildNumber: "10203", Profile: &wantProfile},
+		},
+		{
+			name:  "without a profile",
+			files: map[string][]byte{"Payload/App.app/Info.plist": []byte(xmlInfoPlist)},
+			want:  IPA{BundleID: "io.ionic.starter", Version: "1.2.3", BuildNumber: "10203"},
+		},
+		{
+			name:    "without an app",
+			files:   map[string][]byte{"Payload/App.app/PlugIns/Widget.appex/Info.plist": extensionInfoPlist},
+			wantErr: true,
+		},
+		{
+			name: "invalid profile",
+			files: map[string][]byte{
+				"Payload/App.app/Info.plist":               []byte(xmlInfoPlist),
+				"Payload/App.app/embedded.mobileprovision": []byte("profile"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), "App.ipa")
+			writeIPA(t, pth, tt.files)
+
+			got, err := InspectIPA(pth)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProvisioningProfile_ExportMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile ProvisioningProfile
+		want    string
+	}{
+		{name: "app store", profile: ProvisioningProfile{}, want: ExportMethodAppStore},
+		{name: "ad hoc", profile: ProvisioningProfile{ProvisionedDevices: []string{"udid"}}, want: ExportMethodAdHoc},
+		{
+			name:    "development",
+			profile: ProvisioningProfile{ProvisionedDevices: []string{"udid"}, Entitlements: map[string]interface{}{"get-task-allow": true}},
+			want:    ExportMethodDevelopment,
+		},
+		{name: "enterprise", profile: ProvisioningProfile{ProvisionsAllDevices: true}, want: ExportMethodEnterprise},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.profile.ExportMethod())
+		})
+	}
+}
+
+func TestProvisioningProfile_MatchesBundleID(t *testing.T) {
+	tests := []struct {
+		name     string
+		appID    string
+		bundleID string
+		want     bool
+	}{
+		{name: "explicit", appID: "ABCDE12345.io.ionic.starter", bundleID: "io.ionic.starter", want: true},
+		{name: "other app", appID: "ABCDE12345.io.ionic.other", bundleID: "io.ionic.starter", want: false},
+		{name: "wildcard", appID: "ABCDE12345.*", bundleID: "io.ionic.starter", want: true},
+		{name: "prefixed wildcard", appID: "ABCDE12345.io.ionic.*", bundleID: "io.ionic.starter", want: true},
+		{name: "other prefixed wildcard", appID: "ABCDE12345.com.example.*", bundleID: "io.ionic.starter", want: false},
+		{name: "missing app id", appID: "", bundleID: "io.ionic.starter", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := ProvisioningProfile{Entitlements: map[string]interface{}{"application-identifier": tt.appID}}
+			require.Equal(t, tt.want, profile.MatchesBundleID(tt.bundleID))
+		})
+	}
+}
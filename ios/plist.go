@@ -0,0 +1,367 @@
+// Package ios reads the metadata of the iOS build outputs
+package ios
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ParsePlist parses an XML or a binary property list.
+// The values are map[string]interface{} (dict), []interface{} (array), string, int64 (integer),
+// float64 (real), bool, time.Time (date) and []byte (data).
+func ParsePlist(data []byte) (interface{}, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return parseBinaryPlist(data)
+	}
+	return parseXMLPlist(data)
+}
+
+// parseXMLPlist parses an XML property list
+func parseXMLPlist(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// the DOCTYPE of the plists is not needed to be resolved
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML plist: %s", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local != "plist" {
+				return nil, fmt.Errorf("invalid XML plist: unexpected root element: %s", start.Name.Local)
+			}
+			value, end, err := nextXMLPlistValue(decoder)
+			if err != nil {
+				return nil, fmt.Errorf("invalid XML plist: %s", err)
+			}
+			if end {
+				return nil, errors.New("invalid XML plist: empty plist")
+			}
+			return value, nil
+		}
+	}
+}
+
+// nextXMLPlistValue decodes the next value, end is true if the parent element ends instead
+func nextXMLPlistValue(decoder *xml.Decoder) (value interface{}, end bool, err error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			return nil, true, nil
+		case xml.StartElement:
+			value, err := decodeXMLPlistElement(decoder, t)
+			return value, false, err
+		}
+	}
+}
+
+func decodeXMLPlistElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]interface{}{}
+		for {
+			key, end, err := nextXMLPlistValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				return dict, nil
+			}
+			keyString, ok := key.(xmlPlistKey)
+			if !ok {
+				return nil, errors.New("dict key expected")
+			}
+
+			value, end, err := nextXMLPlistValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				return nil, fmt.Errorf("missing value of key: %s", keyString)
+			}
+			dict[string(keyString)] = value
+		}
+	case "array":
+		array := []interface{}{}
+		for {
+			value, end, err := nextXMLPlistValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if end {
+				return array, nil
+			}
+			array = append(array, value)
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	var text string
+	if err := decoder.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "key":
+		return xmlPlistKey(text), nil
+	case "string":
+		return text, nil
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	}
+	return nil, fmt.Errorf("unsupported element: %s", start.Name.Local)
+}
+
+// xmlPlistKey tells apart the dict keys from the string values
+type xmlPlistKey string
+
+// binaryPlistEpoch is the reference date of the binary plist dates
+var binaryPlistEpoch = time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type binaryPlist struct {
+	data          []byte
+	offsets       []uint64
+	objectRefSize int
+}
+
+// parseBinaryPlist parses a bplist00 property list
+func parseBinaryPlist(data []byte) (interface{}, error) {
+	if len(data) < 8+32 {
+		return nil, errors.New("invalid binary plist: too short")
+	}
+
+	trailer := data[len(data)-32:]
+	offsetIntSize := int(trailer[6])
+	objectRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:])
+	topObject := binary.BigEndian.Uint64(trailer[16:])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:])
+	if offsetIntSize < 1 || offsetIntSize > 8 || objectRefSize < 1 || objectRefSize > 8 ||
+		offsetTableOffset > uint64(len(data)) || numObjects > (uint64(len(data))-offsetTableOffset)/uint64(offsetIntSize) || topObject >= numObjects {
+		return nil, errors.New("invalid binary plist: invalid trailer")
+	}
+
+	p := binaryPlist{data: data, objectRefSize: objectRefSize}
+	for i := uint64(0); i < numObjects; i++ {
+		pos := offsetTableOffset + i*uint64(offsetIntSize)
+		p.offsets = append(p.offsets, readBigEndian(data[pos:pos+uint64(offsetIntSize)]))
+	}
+
+	value, err := p.object(topObject, map[uint64]bool{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid binary plist: %s", err)
+	}
+	return value, nil
+}
+
+func readBigEndian(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// object decodes the object, visiting guards against reference cycles
+func (p binaryPlist) object(ref uint64, visiting map[uint64]bool) (interface{}, error) {
+	if ref >= uint64(len(p.offsets)) {
+		return nil, fmt.Errorf("object reference out of range: %d", ref)
+	}
+	if visiting[ref] {
+		return nil, errors.New("object reference cycle")
+	}
+	visiting[ref] = true
+	defer delete(visiting, ref)
+
+	pos := p.offsets[ref]
+	if pos >= uint64(len(p.data)) {
+		return nil, errors.New("object offset out of range")
+	}
+	marker := p.data[pos]
+	objectType, info := marker>>4, marker&0x0F
+	pos++
+
+	switch objectType {
+	case 0x0:
+		switch info {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1:
+		size := uint64(1) << info
+		b, err := p.bytes(pos, size)
+		if err != nil {
+			return nil, err
+		}
+		if size > 8 {
+			// 128-bit integers are stored for big unsigned values, the low 64 bits hold the value
+			b = b[size-8:]
+		}
+		return int64(readBigEndian(b)), nil
+	case 0x2:
+		size := uint64(1) << info
+		b, err := p.bytes(pos, size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		}
+		if size == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, fmt.Errorf("unsupported real size: %d", size)
+	case 0x3:
+		b, err := p.bytes(pos, 8)
+		if err != nil {
+			return nil, err
+		}
+		seconds := math.Float64frombits(binary.BigEndian.Uint64(b))
+		return binaryPlistEpoch.Add(time.Duration(seconds * float64(time.Second))), nil
+	case 0x4, 0x5, 0x6:
+		length, pos, err := p.length(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		switch objectType {
+		case 0x4:
+			b, err := p.bytes(pos, length)
+			return append([]byte{}, b...), err
+		case 0x5:
+			b, err := p.bytes(pos, length)
+			return string(b), err
+		default:
+			b, err := p.bytes(pos, 2*length)
+			if err != nil {
+				return nil, err
+			}
+			units := make([]uint16, length)
+			for i := range units {
+				units[i] = binary.BigEndian.Uint16(b[2*i:])
+			}
+			return string(utf16.Decode(units)), nil
+		}
+	case 0x8:
+		b, err := p.bytes(pos, uint64(info)+1)
+		if err != nil {
+			return nil, err
+		}
+		return int64(readBigEndian(b)), nil
+	case 0xA, 0xC:
+		count, pos, err := p.length(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := p.refs(pos, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]interface{}, 0, count)
+		for _, r := range refs {
+			value, err := p.object(r, visiting)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case 0xD:
+		count, pos, err := p.length(info, pos)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := p.refs(pos, 2*count)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]interface{}, count)
+		for i := uint64(0); i < count; i++ {
+			key, err := p.object(refs[i], visiting)
+			if err != nil {
+				return nil, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, errors.New("dict key is not a string")
+			}
+			value, err := p.object(refs[count+i], visiting)
+			if err != nil {
+				return nil, err
+			}
+			dict[keyString] = value
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unsupported object type: 0x%x", objectType)
+}
+
+// length returns the length of a data, string or collection object, a length of 0xF is followed by an integer object
+func (p binaryPlist) length(info byte, pos uint64) (uint64, uint64, error) {
+	if info != 0x0F {
+		return uint64(info), pos, nil
+	}
+	b, err := p.bytes(pos, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if b[0]>>4 != 0x1 {
+		return 0, 0, errors.New("invalid length")
+	}
+	size := uint64(1) << (b[0] & 0x0F)
+	lengthBytes, err := p.bytes(pos+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+	length := readBigEndian(lengthBytes)
+	if length > uint64(len(p.data)) {
+		return 0, 0, errors.New("object length out of range")
+	}
+	return length, pos + 1 + size, nil
+}
+
+func (p binaryPlist) refs(pos, count uint64) ([]uint64, error) {
+	b, err := p.bytes(pos, count*uint64(p.objectRefSize))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readBigEndian(b[i*p.objectRefSize : (i+1)*p.objectRefSize])
+	}
+	return refs, nil
+}
+
+func (p binaryPlist) bytes(pos, length uint64) ([]byte, error) {
+	if pos > uint64(len(p.data)) || length > uint64(len(p.data))-pos {
+		return nil, errors.New("object out of range")
+	}
+	return p.data[pos : pos+length], nil
+}
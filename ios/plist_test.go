@@ -0,0 +1,212 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+)
+
+const xmlInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>io.ionic.starter</string>
+	<key>CFBundleShortVersionString</key>
+	<string>1.2.3</string>
+	<key>CFBundleVersion</key>
+	<string>10203</string>
+	<key>UIRequiredDeviceCapabilities</key>
+	<array>
+		<string>arm64</string>
+	</array>
+	<key>LSRequiresIPhoneOS</key>
+	<true/>
+	<key>UIFileSharingEnabled</key>
+	<false/>
+	<key>MinimumOSVersion</key>
+	<real>13.5</real>
+	<key>DTPlatformBuild</key>
+	<integer>-42</integer>
+	<key>BuildDate</key>
+	<date>2023-05-01T10:00:00Z</date>
+	<key>Token</key>
+	<data>
+	aW9u
+	aWM=
+	</data>
+	<key>Empty</key>
+	<dict/>
+</dict>
+</plist>
+`
+
+var infoPlistValue = map[string]interface{}{
+	"CFBundleIdentifier":           "io.ionic.starter",
+	"CFBundleShortVersionString":   "1.2.3",
+	"CFBundleVersion":              "10203",
+	"UIRequiredDeviceCapabilities": []interface{}{"arm64"},
+	"LSRequiresIPhoneOS":           true,
+	"UIFileSharingEnabled":         false,
+	"MinimumOSVersion":             13.5,
+	"DTPlatformBuild":              int64(-42),
+	"BuildDate":                    time.Date(2023, time.May, 1, 10, 0, 0, 0, time.UTC),
+	"Token":                        []byte("ionic"),
+	"Empty":                        map[string]interface{}{},
+}
+
+// buildBinaryPlist encodes the value as a bplist00, with 1 byte object references and 2 byte offsets
+func buildBinaryPlist(value interface{}) []byte {
+	var objects [][]byte
+	var encode func(v interface{}) int
+	encode = func(v interface{}) int {
+		index := len(objects)
+		objects = append(objects, nil)
+
+		var obj bytes.Buffer
+		switch v := v.(type) {
+		case bool:
+			if v {
+				obj.WriteByte(0x09)
+			} else {
+				obj.WriteByte(0x08)
+			}
+		case int64:
+			obj.WriteByte(0x13)
+			writeBigEndian(&obj, uint64(v))
+		case float64:
+			obj.WriteByte(0x23)
+			writeBigEndian(&obj, math.Float64bits(v))
+		case time.Time:
+			obj.WriteByte(0x33)
+			writeBigEndian(&obj, math.Float64bits(v.Sub(binaryPlistEpoch).Seconds()))
+		case []byte:
+			writeMarker(&obj, 0x4, len(v))
+			obj.Write(v)
+		case string:
+			if isASCII(v) {
+				writeMarker(&obj, 0x5, len(v))
+				obj.WriteString(v)
+			} else {
+				units := utf16.Encode([]rune(v))
+				writeMarker(&obj, 0x6, len(units))
+				for _, u := range units {
+					obj.Write([]byte{byte(u >> 8), byte(u)})
+				}
+			}
+		case []interface{}:
+			var refs []byte
+			for _, item := range v {
+				refs = append(refs, byte(encode(item)))
+			}
+			writeMarker(&obj, 0xA, len(v))
+			obj.Write(refs)
+		case map[string]interface{}:
+			var keys []string
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			var keyRefs, valueRefs []byte
+			for _, key := range keys {
+				keyRefs = append(keyRefs, byte(encode(key)))
+				valueRefs = append(valueRefs, byte(encode(v[key])))
+			}
+			writeMarker(&obj, 0xD, len(v))
+			obj.Write(keyRefs)
+			obj.Write(valueRefs)
+		default:
+			panic("unsupported value")
+		}
+		objects[index] = obj.Bytes()
+		return index
+	}
+	encode(value)
+
+	out := bytes.NewBufferString("bplist00")
+	var offsets []uint16
+	for _, obj := range objects {
+		offsets = append(offsets, uint16(out.Len()))
+		out.Write(obj)
+	}
+	offsetTableOffset := out.Len()
+	for _, offset := range offsets {
+		_ = binary.Write(out, binary.BigEndian, offset)
+	}
+	out.Write(make([]byte, 6))
+	out.Write([]byte{2, 1})
+	writeBigEndian(out, uint64(len(objects)))
+	writeBigEndian(out, 0)
+	writeBigEndian(out, uint64(offsetTableOffset))
+	return out.Bytes()
+}
+
+// writeMarker writes the object marker, a length above 14 is written as a following integer object
+func writeMarker(buf *bytes.Buffer, objectType byte, length int) {
+	if length < 0x0F {
+		buf.WriteByte(objectType<<4 | byte(length))
+		return
+	}
+	buf.WriteByte(objectType<<4 | 0x0F)
+	buf.WriteByte(0x11)
+	buf.Write([]byte{byte(length >> 8), byte(length)})
+}
+
+func writeBigEndian(buf *bytes.Buffer, v uint64) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	buf.Write(b)
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_ParsePlist(t *testing.T) {
+	unicodeValue := map[string]interface{}{
+		"CFBundleDisplayName": "Ionic Alkalmazás",
+		"Description":         "a long string, which length does not fit into the marker",
+	}
+
+	cyclic := buildBinaryPlist([]interface{}{"item"})
+	// point the array's reference to itself
+	cyclic[len("bplist00")+1] = 0
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "XML", data: []byte(xmlInfoPlist), want: infoPlistValue},
+		{name: "binary", data: buildBinaryPlist(infoPlistValue), want: infoPlistValue},
+		{name: "binary with UTF-16 and long strings", data: buildBinaryPlist(unicodeValue), want: unicodeValue},
+		{name: "XML without a plist root", data: []byte("<dict></dict>"), wantErr: true},
+		{name: "XML with a dict value without key", data: []byte("<plist><dict><string>a</string></dict></plist>"), wantErr: true},
+		{name: "XML with an invalid integer", data: []byte("<plist><integer>a</integer></plist>"), wantErr: true},
+		{name: "truncated binary", data: buildBinaryPlist(infoPlistValue)[:60], wantErr: true},
+		{name: "binary with a reference cycle", data: cyclic, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePlist(tt.data)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package ios
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Export methods, as the exportOptions.plist and the build.json packageType name them
+const (
+	ExportMethodAppStore    = "app-store"
+	ExportMethodAdHoc       = "ad-hoc"
+	ExportMethodEnterprise  = "enterprise"
+	ExportMethodDevelopment = "development"
+)
+
+// ProvisioningProfile is the subset of the embedded.mobileprovision used by the step
+type ProvisioningProfile struct {
+	Name                 string
+	UUID                 string
+	TeamID               string
+	TeamName             string
+	ExpirationDate       time.Time
+	ProvisionsAllDevices bool
+	ProvisionedDevices   []string
+	Entitlements         map[string]interface{}
+}
+
+// oidSignedData is the content type of the CMS signed message wrapping the profile plist
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// ParseProvisioningProfile reads the plist of the CMS signed provisioning profile, the signature is not verified
+func ParseProvisioningProfile(data []byte) (ProvisioningProfile, error) {
+	content, err := signedDataContent(data)
+	if err != nil {
+		// fall back to locating the plist in the message, it is stored unencoded
+		start := bytes.Index(data, []byte("<?xml"))
+		end := bytes.LastIndex(data, []byte("</plist>"))
+		if start < 0 || end < start {
+			return ProvisioningProfile{}, fmt.Errorf("no plist found in the provisioning profile: %s", err)
+		}
+		content = data[start : end+len("</plist>")]
+	}
+
+	value, err := ParsePlist(content)
+	if err != nil {
+		return ProvisioningProfile{}, err
+	}
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return ProvisioningProfile{}, errors.New("the provisioning profile plist is not a dict")
+	}
+
+	profile := ProvisioningProfile{
+		Name:                 stringValue(dict, "Name"),
+		UUID:                 stringValue(dict, "UUID"),
+		TeamName:             stringValue(dict, "TeamName"),
+		ProvisionsAllDevices: boolValue(dict, "ProvisionsAllDevices"),
+		ProvisionedDevices:   stringsValue(dict, "ProvisionedDevices"),
+	}
+	if teamIDs := stringsValue(dict, "TeamIdentifier"); len(teamIDs) > 0 {
+		profile.TeamID = teamIDs[0]
+	}
+	if expirationDate, ok := dict["ExpirationDate"].(time.Time); ok {
+		profile.ExpirationDate = expirationDate
+	}
+	if entitlements, ok := dict["Entitlements"].(map[string]interface{}); ok {
+		profile.Entitlements = entitlements
+		if profile.TeamID == "" {
+			profile.TeamID = stringValue(entitlements, "com.apple.developer.team-identifier")
+		}
+	}
+	return profile, nil
+}
+
+// signedDataContent returns the encapsulated content of a DER encoded CMS SignedData message
+func signedDataContent(data []byte) ([]byte, error) {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		// Content is the [0] tagged SignedData
+		Content asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(data, &contentInfo); err != nil {
+		return nil, err
+	}
+	if !contentInfo.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected content type: %s", contentInfo.ContentType)
+	}
+	if contentInfo.Content.Class != asn1.ClassContextSpecific || contentInfo.Content.Tag != 0 {
+		return nil, errors.New("missing signed data")
+	}
+
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo struct {
+			ContentType asn1.ObjectIdentifier
+			Content     []byte `asn1:"explicit,optional,tag:0"`
+		}
+		// the certificates and the signer infos are not needed
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, err
+	}
+	if len(signedData.EncapContentInfo.Content) == 0 {
+		return nil, errors.New("detached content")
+	}
+	return signedData.EncapContentInfo.Content, nil
+}
+
+// ExportMethod returns the distribution type of the profile
+func (p ProvisioningProfile) ExportMethod() string {
+	switch {
+	case p.ProvisionsAllDevices:
+		return ExportMethodEnterprise
+	case len(p.ProvisionedDevices) > 0 && boolValue(p.Entitlements, "get-task-allow"):
+		return ExportMethodDevelopment
+	case len(p.ProvisionedDevices) > 0:
+		return ExportMethodAdHoc
+	}
+	return ExportMethodAppStore
+}
+
+// ApplicationIdentifier returns the <team id>.<bundle id> the profile is for, the bundle id may be a wildcard
+func (p ProvisioningProfile) ApplicationIdentifier() string {
+	if id := stringValue(p.Entitlements, "application-identifier"); id != "" {
+		return id
+	}
+	// macOS profiles
+	return stringValue(p.Entitlements, "com.apple.application-identifier")
+}
+
+// MatchesBundleID tells whether the profile can sign the bundle id, taking wildcard profiles into account
+func (p ProvisioningProfile) MatchesBundleID(bundleID string) bool {
+	appID := p.ApplicationIdentifier()
+	if appID == "" {
+		return false
+	}
+	if i := strings.Index(appID, "."); i >= 0 {
+		appID = appID[i+1:]
+	}
+	if strings.HasSuffix(appID, "*") {
+		return strings.HasPrefix(bundleID, strings.TrimSuffix(appID, "*"))
+	}
+	return appID == bundleID
+}
+
+func stringValue(dict map[string]interface{}, key string) string {
+	s, _ := dict[key].(string)
+	return s
+}
+
+func boolValue(dict map[string]interface{}, key string) bool {
+	b, _ := dict[key].(bool)
+	return b
+}
+
+func stringsValue(dict map[string]interface{}, key string) []string {
+	array, _ := dict[key].([]interface{})
+	var values []string
+	for _, v := range array {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
@@ -1,11 +1,25 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-ionic-archive/ios"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const (
+	iosBundleIDEnvKey      = "BITRISE_IOS_BUNDLE_ID"
+	iosVersionEnvKey       = "BITRISE_IOS_VERSION"
+	iosBuildNumberEnvKey   = "BITRISE_IOS_BUILD_NUMBER"
+	iosTeamIDEnvKey        = "BITRISE_IOS_TEAM_ID"
+	iosExportMethodEnvKey  = "BITRISE_IOS_EXPORT_METHOD"
+	iosProfileExpiryEnvKey = "BITRISE_IOS_PROFILE_EXPIRY"
+	iosEntitlementsEnvKey  = "BITRISE_IOS_ENTITLEMENTS"
 )
 
 func getIosOutputCandidateDirsPaths(workDir string, target string, configuration string) []string {
@@ -23,18 +37,77 @@ func getIosOutputCandidateDirsPaths(workDir string, target string, configuration
 	}
 }
 
-func findFirstExistingDir(candidateDirPaths []string) string {
-	for _, path := range candidateDirPaths {
-		exist, err := pathutil.IsDirExists(path)
+// inspectIPAs reads the Info.plist and the embedded provisioning profile of the exported IPAs and records them in the artifact manifest.
+// The signing mistakes are reported, but they do not fail the step.
+func inspectIPAs(manifest *artifactManifest, deployedPaths []string, signing iosBuildConfig, now time.Time) map[string]ios.IPA {
+	ipas := map[string]ios.IPA{}
+	for _, pth := range deployedPaths {
+		ipa, err := ios.InspectIPA(pth)
 		if err != nil {
-			log.Warnf("Failed to check if dir (%s) exist: %s", path, err)
+			log.Warnf("Failed to inspect %s, error: %s", pth, err)
 			continue
 		}
+		ipas[pth] = ipa
+		manifest.setIOSApp(pth, ipa)
+
+		for _, issue := range ipaSigningIssues(ipa, signing, now) {
+			log.Warnf("%s: %s", filepath.Base(pth), issue)
+		}
+	}
+	return ipas
+}
+
+// ipaSigningIssues compares the embedded provisioning profile with the app and the signing properties of the build.json
+func ipaSigningIssues(ipa ios.IPA, signing iosBuildConfig, now time.Time) []string {
+	profile := ipa.Profile
+	if profile == nil {
+		return []string{"no embedded provisioning profile found"}
+	}
+
+	var issues []string
+	if !profile.MatchesBundleID(ipa.BundleID) {
+		issues = append(issues, fmt.Sprintf("the provisioning profile (%s) is for %s, not for the bundle id %s", profile.Name, profile.ApplicationIdentifier(), ipa.BundleID))
+	}
+	if method := profile.ExportMethod(); signing.PackageType != "" && signing.PackageType != method {
+		issues = append(issues, fmt.Sprintf("the provisioning profile (%s) has the %s export method, but the %s package type is configured", profile.Name, method, signing.PackageType))
+	}
+	if signing.DevelopmentTeam != "" && signing.DevelopmentTeam != profile.TeamID {
+		issues = append(issues, fmt.Sprintf("the provisioning profile (%s) belongs to the team %s, but the team %s is configured", profile.Name, profile.TeamID, signing.DevelopmentTeam))
+	}
+	if !profile.ExpirationDate.IsZero() && profile.ExpirationDate.Before(now) {
+		issues = append(issues, fmt.Sprintf("the provisioning profile (%s) expired at %s", profile.Name, profile.ExpirationDate.Format(time.RFC3339)))
+	}
+	return issues
+}
 
-		if exist {
-			return path
+// exportIOSArtifact exports the details of the last exported IPA:
+// the bundle id and the version from its Info.plist,
+// the team id, the export method, the expiry (RFC3339) and the entitlements (JSON) of its provisioning profile.
+func exportIOSArtifact(r runner.Runner, ipa ios.IPA) error {
+	type env struct{ name, key, value string }
+	envs := []env{
+		{"bundle id", iosBundleIDEnvKey, ipa.BundleID},
+		{"version", iosVersionEnvKey, ipa.Version},
+		{"build number", iosBuildNumberEnvKey, ipa.BuildNumber},
+	}
+	if profile := ipa.Profile; profile != nil {
+		entitlements, err := json.Marshal(profile.Entitlements)
+		if err != nil {
+			return fmt.Errorf("failed to encode the entitlements: %s", err)
 		}
+		envs = append(envs,
+			env{"team id", iosTeamIDEnvKey, profile.TeamID},
+			env{"export method", iosExportMethodEnvKey, profile.ExportMethod()},
+			env{"provisioning profile expiry", iosProfileExpiryEnvKey, profile.ExpirationDate.Format(time.RFC3339)},
+			env{"entitlements", iosEntitlementsEnvKey, string(entitlements)},
+		)
 	}
 
-	return ""
+	for _, env := range envs {
+		if err := exportEnvironment(r, env.key, env.value); err != nil {
+			return err
+		}
+		log.Donef("The %s is now available in the Environment Variable: %s (value: %s)", env.name, env.key, env.value)
+	}
+	return nil
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"testing"
+	"time"
 
+	"github.com/bitrise-steplib/steps-ionic-archive/ios"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,3 +42,57 @@ func Test_getIosOutputCandidateDirsPaths(t *testing.T) {
 		})
 	}
 }
+
+func Test_ipaSigningIssues(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	appStoreProfile := &ios.ProvisioningProfile{
+		Name:           "App Store",
+		TeamID:         "ABCDE12345",
+		ExpirationDate: now.AddDate(1, 0, 0),
+		Entitlements:   map[string]interface{}{"application-identifier": "ABCDE12345.io.ionic.starter"},
+	}
+	expiredProfile := *appStoreProfile
+	expiredProfile.ExpirationDate = now.AddDate(0, 0, -1)
+
+	tests := []struct {
+		name    string
+		ipa     ios.IPA
+		signing iosBuildConfig
+		want    []string
+	}{
+		{
+			name:    "matching profile",
+			ipa:     ios.IPA{BundleID: "io.ionic.starter", Profile: appStoreProfile},
+			signing: iosBuildConfig{PackageType: "app-store", DevelopmentTeam: "ABCDE12345"},
+		},
+		{
+			name: "no signing config",
+			ipa:  ios.IPA{BundleID: "io.ionic.starter", Profile: appStoreProfile},
+		},
+		{
+			name: "missing profile",
+			ipa:  ios.IPA{BundleID: "io.ionic.starter"},
+			want: []string{"no embedded provisioning profile found"},
+		},
+		{
+			name:    "wrong profile",
+			ipa:     ios.IPA{BundleID: "io.ionic.other", Profile: appStoreProfile},
+			signing: iosBuildConfig{PackageType: "ad-hoc", DevelopmentTeam: "FGHIJ67890"},
+			want: []string{
+				"the provisioning profile (App Store) is for ABCDE12345.io.ionic.starter, not for the bundle id io.ionic.other",
+				"the provisioning profile (App Store) has the app-store export method, but the ad-hoc package type is configured",
+				"the provisioning profile (App Store) belongs to the team ABCDE12345, but the team FGHIJ67890 is configured",
+			},
+		},
+		{
+			name: "expired profile",
+			ipa:  ios.IPA{BundleID: "io.ionic.starter", Profile: &expiredProfile},
+			want: []string{"the provisioning profile (App Store) expired at 2023-12-31T00:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, ipaSigningIssues(tt.ipa, tt.signing, now))
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,27 +13,35 @@ import (
 
 	"github.com/bitrise-io/go-steputils/jsdependency"
 	"github.com/bitrise-io/go-steputils/stepconf"
-	"github.com/bitrise-io/go-steputils/tools"
 	"github.com/bitrise-io/go-utils/colorstring"
-	"github.com/bitrise-io/go-utils/command"
 	"github.com/bitrise-io/go-utils/errorutil"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
 	"github.com/bitrise-io/go-utils/sliceutil"
-	"github.com/bitrise-io/go-utils/ziputil"
+	"github.com/bitrise-steplib/steps-ionic-archive/android"
+	"github.com/bitrise-steplib/steps-ionic-archive/diagnosis"
+	"github.com/bitrise-steplib/steps-ionic-archive/discovery"
 	"github.com/bitrise-steplib/steps-ionic-archive/ionic"
+	"github.com/bitrise-steplib/steps-ionic-archive/phaselog"
+	"github.com/bitrise-steplib/steps-ionic-archive/project"
+	"github.com/bitrise-steplib/steps-ionic-archive/redact"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/bitrise-steplib/steps-ionic-archive/signing"
 	ver "github.com/hashicorp/go-version"
 	shellquote "github.com/kballard/go-shellquote"
 )
 
 const (
-	ipaPathEnvKey = "BITRISE_IPA_PATH"
+	ipaPathEnvKey     = "BITRISE_IPA_PATH"
+	ipaPathListEnvKey = "BITRISE_IPA_PATH_LIST"
 
-	appZipPathEnvKey = "BITRISE_APP_PATH"
-	appDirPathEnvKey = "BITRISE_APP_DIR_PATH"
+	appZipPathEnvKey     = "BITRISE_APP_PATH"
+	appZipPathListEnvKey = "BITRISE_APP_PATH_LIST"
+	appDirPathEnvKey     = "BITRISE_APP_DIR_PATH"
 
-	dsymDirPathEnvKey = "BITRISE_DSYM_DIR_PATH"
-	dsymZipPathEnvKey = "BITRISE_DSYM_PATH"
+	dsymDirPathEnvKey     = "BITRISE_DSYM_DIR_PATH"
+	dsymZipPathEnvKey     = "BITRISE_DSYM_PATH"
+	dsymZipPathListEnvKey = "BITRISE_DSYM_PATH_LIST"
 
 	apkPathEnvKey     = "BITRISE_APK_PATH"
 	apkPathListEnvKey = "BITRISE_APK_PATH_LIST"
@@ -40,27 +51,51 @@ const (
 
 type config struct {
 	Platform      string `env:"platform,opt['ios,android',ios,android]"`
+	Integration   string `env:"integration,opt[auto,cordova,capacitor]"`
 	Configuration string `env:"configuration,required"`
 	Target        string `env:"target,required"`
 	BuildConfig   string `env:"build_config"`
 	Options       string `env:"options"`
 
-	Username string `env:"ionic_username"`
-	Password string `env:"ionic_password"`
+	IOSOptions     string `env:"ios_options"`
+	AndroidOptions string `env:"android_options"`
 
-	RunPrepare     bool   `env:"run_ionic_prepare,opt[true,false]"`
-	IonicVersion   string `env:"ionic_version"`
-	CordovaVersion string `env:"cordova_version"`
+	Username stepconf.Secret `env:"ionic_username"`
+	Password stepconf.Secret `env:"ionic_password"`
+	Token    stepconf.Secret `env:"ionic_token"`
+
+	RunPrepare        bool   `env:"run_ionic_prepare,opt[true,false]"`
+	ParallelBuilds    bool   `env:"parallel_builds,opt[true,false]"`
+	ContinueOnFailure bool   `env:"continue_on_failure,opt[true,false]"`
+	IonicVersion      string `env:"ionic_version"`
+	CordovaVersion    string `env:"cordova_version"`
 
 	WorkDir   string `env:"workdir,dir"`
 	DeployDir string `env:"BITRISE_DEPLOY_DIR"`
 
-	AndroidAppType string `env:"android_app_type,opt[apk,aab]"`
+	BuildURL      string `env:"BITRISE_BUILD_URL"`
+	GitCommit     string `env:"GIT_CLONE_COMMIT_HASH"`
+	RepositoryURL string `env:"GIT_REPOSITORY_URL"`
+
+	AndroidAppType       string `env:"android_app_type,opt[apk,aab]"`
+	ArtifactNameTemplate string `env:"artifact_name_template"`
+
+	AndroidPolicyCheck         string   `env:"android_policy_check,opt[off,warn,fail]"`
+	AndroidMinTargetSDK        int      `env:"android_min_target_sdk"`
+	AndroidPermissionAllowlist []string `env:"android_permission_allowlist,multiline"`
+
+	MaxIPASize string `env:"max_ipa_size"`
+	MaxAPKSize string `env:"max_apk_size"`
+	MaxAABSize string `env:"max_aab_size"`
+
+	PreviousArtifacts string `env:"previous_artifacts"`
+
+	SigningKey stepconf.Secret `env:"signing_key"`
 
 	UseCache bool `env:"cache_local_deps,opt[true,false]"`
 }
 
-func installDependency(packageManager jsdependency.Tool, name string, version string) error {
+func installDependency(r runner.Runner, packageManager jsdependency.Tool, name string, version string) error {
 	fmt.Println()
 	log.Infof("Updating %s version to: %s", name, version)
 
@@ -73,20 +108,21 @@ func installDependency(packageManager jsdependency.Tool, name string, version st
 		log.Donef("$ %s", cmd.Slice.PrintableCommandArgs())
 
 		// Yarn returns an error if the package is not added before removal, ignoring
-		if out, err := cmd.Slice.RunAndReturnTrimmedCombinedOutput(); err != nil && !cmd.IgnoreError {
+		if out, err := r.RunAndReturnTrimmedCombinedOutput(runner.FromModel(cmd.Slice)); err != nil && !cmd.IgnoreError {
 			if errorutil.IsExitStatusError(err) {
-				return fmt.Errorf("Failed to update %s version, output: %s", name, out)
+				return fmt.Errorf("Failed to update %s version, output: %s, error: %w", name, out, err)
 			}
-			return fmt.Errorf("Failed to update %s version, error: %s", name, err)
+			return fmt.Errorf("Failed to update %s version, error: %w", name, err)
 		}
 	}
 	return nil
 }
 
-func moveAndExportOutputs(outputs []string, deployDir, envKey string, envListKey string) (string, []string, error) {
+func moveAndExportOutputs(r runner.Runner, manifest *artifactManifest, namer *artifactNamer, outputs []string, deployDir, envKey string, envListKey string) (string, []string, error) {
 	outputToExport := ""
 	APKPaths := make([]string, len(outputs))
 	for x, output := range outputs {
+		sourcePth := output
 		info, err := os.Lstat(output)
 		if err != nil {
 			return "", []string{}, err
@@ -119,19 +155,22 @@ func moveAndExportOutputs(outputs []string, deployDir, envKey string, envListKey
 			info = resolvedInfo
 		}
 
-		fileName := filepath.Base(output)
-		destinationPth := filepath.Join(deployDir, fileName)
+		destinationPth := filepath.Join(deployDir, namer.name(output))
 
 		if info.IsDir() {
-			if err := command.CopyDir(output, destinationPth, false); err != nil {
+			if err := copyDir(output, destinationPth); err != nil {
 				return "", []string{}, err
 			}
 		} else {
-			if err := command.CopyFile(output, destinationPth); err != nil {
+			if err := copyFile(output, destinationPth); err != nil {
 				return "", []string{}, err
 			}
 		}
 
+		if err := manifest.add(sourcePth, destinationPth); err != nil {
+			return "", []string{}, err
+		}
+
 		outputToExport = destinationPth
 		APKPaths[x] = destinationPth
 	}
@@ -140,36 +179,71 @@ func moveAndExportOutputs(outputs []string, deployDir, envKey string, envListKey
 		return "", []string{}, nil
 	}
 
-	if err := tools.ExportEnvironmentWithEnvman(envKey, outputToExport); err != nil {
+	if err := exportEnvironment(r, envKey, outputToExport); err != nil {
 		return "", []string{}, err
 	}
 
-	if err := tools.ExportEnvironmentWithEnvman(envListKey, strings.Join(APKPaths, "|")); err != nil {
-		return "", []string{}, err
+	if envListKey != "" {
+		if err := exportEnvironment(r, envListKey, strings.Join(APKPaths, "|")); err != nil {
+			return "", []string{}, err
+		}
 	}
 
 	return outputToExport, APKPaths, nil
 }
 
-func fail(format string, v ...interface{}) {
-	log.Errorf(format, v...)
-	os.Exit(1)
-}
-
-func findArtifact(dir, ext string, buildStart time.Time) ([]string, error) {
-	var matches []string
-	if walkErr := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
-		if fi.ModTime().Before(buildStart) {
-			return nil
+// zipAndExportDirs zips every exported directory (.dSYM, .app) next to it, and exports the last zip's path and the list of the zip paths
+func zipAndExportDirs(r runner.Runner, manifest *artifactManifest, dirs []string, envKey, envListKey string) ([]string, error) {
+	zippedPaths := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		zippedPth := dir + ".zip"
+		if err := zipDir(r, dir, zippedPth); err != nil {
+			return nil, fmt.Errorf("failed to zip dir (%s), error: %s", dir, err)
 		}
-		if filepath.Ext(path) == "."+ext {
-			matches = append(matches, path)
+		if err := manifest.addZip(dir, zippedPth); err != nil {
+			return nil, err
 		}
-		return err
-	}); walkErr != nil {
-		return nil, walkErr
+		zippedPaths = append(zippedPaths, zippedPth)
+	}
+
+	if len(zippedPaths) == 0 {
+		return zippedPaths, nil
+	}
+
+	if err := exportEnvironment(r, envKey, zippedPaths[len(zippedPaths)-1]); err != nil {
+		return nil, err
+	}
+	if err := exportEnvironment(r, envListKey, strings.Join(zippedPaths, "|")); err != nil {
+		return nil, err
+	}
+	return zippedPaths, nil
+}
+
+// exportEnvironment exports the env with envman, the value is passed on stdin as it may contain special characters
+func exportEnvironment(r runner.Runner, key, value string) error {
+	cmd := runner.New("envman", "add", "--key", key)
+	cmd.SetStdin(strings.NewReader(value))
+	return r.Run(cmd)
+}
+
+// ionicLogout removes the session stored by ionic login, a failed logout does not fail the step
+func ionicLogout(r runner.Runner) {
+	fmt.Println()
+	log.Infof("Ionic logout")
+
+	cmd := ionic.LogoutCommand()
+	cmd.SetStdout(os.Stdout).SetStderr(os.Stderr)
+
+	log.Donef("$ %s", cmd.PrintableCommandArgs())
+
+	if err := r.Run(cmd); err != nil {
+		log.Warnf("ionic logout command failed, error: %s", err)
 	}
-	return matches, nil
+}
+
+func fail(format string, v ...interface{}) {
+	log.Errorf(format, v...)
+	os.Exit(1)
 }
 
 func main() {
@@ -178,20 +252,37 @@ func main() {
 	if err := stepconf.Parse(&configs); err != nil {
 		fail("Could not create config: %s", err)
 	}
+
+	// the log and the output of the commands are redacted from here
+	secrets := redact.NewSecrets(sensitiveValues(configs))
+	log.SetOutWriter(redact.NewWriter(os.Stdout, secrets))
+	if unmasked := secrets.Unmasked(); unmasked > 0 {
+		log.Warnf("%d password(s) of the build config and the options are shorter than %d characters, they can not be masked in the log", unmasked, redact.MinSecretLength)
+	}
+
+	printedConfigs := configs
+	printedConfigs.Options = secrets.Redact(configs.Options)
+	printedConfigs.IOSOptions = secrets.Redact(configs.IOSOptions)
+	printedConfigs.AndroidOptions = secrets.Redact(configs.AndroidOptions)
 	fmt.Println()
-	stepconf.Print(configs)
+	stepconf.Print(printedConfigs)
 
-	isAAB := configs.AndroidAppType == "aab"
+	if err := run(configs, redact.NewRunner(runner.NewDefault(), secrets)); err != nil {
+		fail("%s", err)
+	}
+}
 
+// run archives the project, every external command is executed through the runner
+func run(configs config, r runner.Runner) (err error) {
 	// Change dir to working directory
 	workDir, err := pathutil.AbsPath(configs.WorkDir)
 	if err != nil {
-		fail("Failed to expand WorkDir (%s), error: %s", configs.WorkDir, err)
+		return fmt.Errorf("Failed to expand WorkDir (%s), error: %s", configs.WorkDir, err)
 	}
 
 	currentDir, err := pathutil.CurrentWorkingDirectoryAbsolutePath()
 	if err != nil {
-		fail("Failed to get current directory, error: %s", err)
+		return fmt.Errorf("Failed to get current directory, error: %s", err)
 	}
 
 	if workDir != currentDir {
@@ -200,26 +291,83 @@ func main() {
 
 		revokeFunc, err := pathutil.RevokableChangeDir(workDir)
 		if err != nil {
-			fail("Failed to change working directory, error: %s", err)
+			return fmt.Errorf("Failed to change working directory, error: %s", err)
 		}
 		defer func() {
 			fmt.Println()
 			log.Infof("Reset working directory")
-			if err := revokeFunc(); err != nil {
-				fail("Failed to reset working directory, error: %s", err)
+			if revokeErr := revokeFunc(); revokeErr != nil && err == nil {
+				err = fmt.Errorf("Failed to reset working directory, error: %s", revokeErr)
 			}
 		}()
 	}
 
+	// the output of the failed command, which failed the step, is matched against the known failures
+	if err := archive(workDir, configs, diagnosis.NewRunner(r)); err != nil {
+		exportFailureCategory(r, err)
+		return err
+	}
+	return nil
+}
+
+// archive builds the selected platforms in the working directory and exports the artifacts
+func archive(workDir string, configs config, r runner.Runner) error {
+	timer := newPhaseTimer(time.Now)
+	attestation, r := newBuildProvenance(configs, workDir, r)
+	phases := phaselog.NewRunner(r, configs.DeployDir, time.Now(), redact.NewSecrets(sensitiveValues(configs)))
+	r = phases
+	defer func() {
+		exportPhaseLogs(phases)
+		reportTimings(phases, timer, configs.DeployDir)
+	}()
+	isAAB := configs.AndroidAppType == "aab"
+
+	platforms := strings.Split(configs.Platform, ",")
+	for i, p := range platforms {
+		platforms[i] = strings.TrimSpace(p)
+	}
+	sort.Strings(platforms)
+
+	integration, err := resolveIntegration(workDir, configs.Integration, platforms)
+	if err != nil {
+		return err
+	}
+	isCapacitor := integration == integrationCapacitor
+	attestation.internal.Integration = integration
+
+	app, err := project.ReadApp(workDir, project.Integration(integration))
+	if err != nil {
+		log.Warnf("Failed to read the app id and version, error: %s", err)
+	}
+	namer, err := newArtifactNamer(configs.ArtifactNameTemplate, app)
+	if err != nil {
+		return fmt.Errorf("Invalid artifact name template, error: %s", err)
+	}
+	budgets, err := newSizeBudgets(configs)
+	if err != nil {
+		return fmt.Errorf("Invalid size budget, error: %s", err)
+	}
+	var signingKey ed25519.PrivateKey
+	if configs.SigningKey != "" {
+		if signingKey, err = signing.ParsePrivateKey(string(configs.SigningKey)); err != nil {
+			return fmt.Errorf("Invalid signing key, error: %s", err)
+		}
+	}
+
 	// Update cordova and ionic version
+	startPhase(phases, phaseInstall)
 	packageManager, err := jsdependency.DetectTool(workDir)
 	if err != nil {
 		log.Warnf("%s", err)
 	}
 	log.Printf("Js package manager used: %s", packageManager)
+	attestation.internal.PackageManager = string(packageManager)
+	if configs.CordovaVersion != "" || configs.IonicVersion != "" {
+		timer.enter(timingDependencyInstall)
+	}
 	if configs.CordovaVersion != "" {
-		if err := installDependency(packageManager, "cordova", configs.CordovaVersion); err != nil {
-			fail("%s", err)
+		if err := installDependency(r, packageManager, "cordova", configs.CordovaVersion); err != nil {
+			return err
 		}
 	}
 	if configs.IonicVersion != "" {
@@ -228,223 +376,338 @@ func main() {
 			log.Warnf("%s", err)
 		}
 
-		if err := installDependency(packageManager, packageName, configs.IonicVersion); err != nil {
-			fail("%s", err)
+		if err := installDependency(r, packageManager, packageName, configs.IonicVersion); err != nil {
+			return err
 		}
 	}
 
+	timer.enter(timingVersionDetection)
 	fmt.Println()
-	cordovaVersion, err := ionic.CordovaVersion()
-	if err != nil {
-		fail("Failed to get cordova version, error: %s", err)
-	}
-
-	log.Printf("cordova version: %s", colorstring.Green(cordovaVersion.String()))
-
-	if isAAB {
-		minCordovaVersion, err := ver.NewVersion("8.1.0")
+	if !isCapacitor {
+		cordovaVersion, err := ionic.CordovaVersion(r)
 		if err != nil {
-			fail("Failed to parse version 8.1.0: %s", err)
+			return fmt.Errorf("Failed to get cordova version, error: %w", err)
 		}
-		if cordovaVersion.LessThan(minCordovaVersion) {
-			log.Warnf("Cordova doesn't support exporting aab, falling back to apk")
-			isAAB = false
+
+		log.Printf("cordova version: %s", colorstring.Green(cordovaVersion.String()))
+		attestation.internal.CordovaVersion = cordovaVersion.String()
+
+		if isAAB {
+			minCordovaVersion, err := ver.NewVersion("8.1.0")
+			if err != nil {
+				return fmt.Errorf("Failed to parse version 8.1.0: %s", err)
+			}
+			if cordovaVersion.LessThan(minCordovaVersion) {
+				log.Warnf("Cordova doesn't support exporting aab, falling back to apk")
+				isAAB = false
+			}
 		}
 	}
 
-	ionicVer, err := ionic.Version()
+	ionicVer, err := ionic.Version(r)
 	if err != nil {
-		fail("Failed to get ionic version, error: %s", err)
+		return fmt.Errorf("Failed to get ionic version, error: %w", err)
 	}
 
 	log.Printf("ionic version: %s", colorstring.Green(ionicVer.String()))
+	attestation.internal.IonicVersion = ionicVer.String()
+	timer.leave()
 
 	// Ionic CLI plugins angular and cordova have been marked as deprecated for
 	// version 3.8.0 and above.
 	ionicVerConstraint, err := ver.NewConstraint("< 3.8.0")
 	if err != nil {
-		fail("Could not create version constraint for ionic: %s", err)
+		return fmt.Errorf("Could not create version constraint for ionic: %s", err)
 	}
 	if ionicVerConstraint.Check(ionicVer) {
 		fmt.Println()
 		log.Infof("Installing cordova and angular plugins")
-		cmd, err := jsdependency.AddCommand(packageManager, jsdependency.Local, "@ionic/cli-plugin-ionic-angular@latest", "@ionic/cli-plugin-cordova@latest")
+		timer.enter(timingPluginInstall)
+		addCmd, err := jsdependency.AddCommand(packageManager, jsdependency.Local, "@ionic/cli-plugin-ionic-angular@latest", "@ionic/cli-plugin-cordova@latest")
 		if err != nil {
-			fail("%s", err)
+			return err
 		}
+		cmd := runner.FromModel(addCmd)
 		fmt.Println()
 		log.Donef("$ %s", cmd.PrintableCommandArgs())
 		fmt.Println()
 
-		if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		if out, err := r.RunAndReturnTrimmedCombinedOutput(cmd); err != nil {
 			if errorutil.IsExitStatusError(err) {
-				fail("Failed to install: %s failed, output: %s", cmd.PrintableCommandArgs(), out)
+				return fmt.Errorf("Failed to install: %s failed, output: %s, error: %w", cmd.PrintableCommandArgs(), out, err)
 			}
-			fail("Failed to install: %s failed, error: %s", cmd.PrintableCommandArgs(), err)
+			return fmt.Errorf("Failed to install: %s failed, error: %w", cmd.PrintableCommandArgs(), err)
 		}
+		timer.leave()
 	}
 
 	// ionic login
-	if configs.Username != "" && configs.Password != "" {
+	if configs.Token != "" {
 		fmt.Println()
 		log.Infof("Ionic login")
+		log.Printf("The ionic commands are authenticated with the ionic token (%s)", ionic.TokenEnvKey)
 
-		cmd := ionic.LoginCommand(configs.Username, configs.Password)
+		r = runner.NewToolEnvs(r, "ionic", ionic.TokenEnvKey+"="+string(configs.Token))
+	} else if configs.Username != "" && configs.Password != "" {
+		fmt.Println()
+		log.Infof("Ionic login")
+		log.Warnf("The ionic password is passed to ionic login as an argument, which is visible in the process list, use the Ionic token input instead")
+		startPhase(phases, phaseLogin)
+		timer.enter(timingLogin)
+
+		cmd := ionic.LoginCommand(string(configs.Username), string(configs.Password))
 		cmd.SetStdout(os.Stdout).SetStderr(os.Stderr).SetStdin(strings.NewReader("y"))
 
 		log.Donef("$ ionic login *** ***")
 
-		if err := cmd.Run(); err != nil {
-			fail("ionic login command failed, error: %s", err)
+		if err := r.Run(cmd); err != nil {
+			return fmt.Errorf("ionic login command failed, error: %w", err)
 		}
+		timer.leave()
+		// the session is stored in the ionic config of the user, it would be kept on a shared agent
+		defer func() {
+			// the logout is appended to the log of the login phase, it runs before the phase logs are exported
+			startPhase(phases, phaseLogin)
+			ionicLogout(r)
+		}()
 	}
 
 	ionicMajorVersion := ionicVer.Segments()[0]
 
-	platforms := strings.Split(configs.Platform, ",")
-	for i, p := range platforms {
-		platforms[i] = strings.TrimSpace(p)
+	parallel := configs.ParallelBuilds
+	if parallel && !isCapacitor && ionicMajorVersion < 4 {
+		log.Warnf("Building the platforms concurrently requires ionic 4 or later (ionic cordova build --no-build), the platforms are built one after the other")
+		parallel = false
 	}
-	sort.Strings(platforms)
 
 	// ionic prepare
 	fmt.Println()
 	log.Infof("Building project")
 
-	if configs.RunPrepare {
+	// the concurrent cordova builds are prepared together with the web build (see below)
+	if configs.RunPrepare && !isCapacitor && !parallel {
+		startPhase(phases, phasePrepare)
+		timer.enter(timingPrepare)
 		cmd := ionic.PrepareCommand(ionicMajorVersion)
 		cmd.SetStdout(os.Stdout).SetStderr(os.Stderr)
 
 		log.Donef("$ %s", cmd.PrintableCommandArgs())
 
-		if err := cmd.Run(); err != nil {
-			fail("ionic prepare command %s failed, error: %s", cmd.PrintableCommandArgs(), err)
+		if err := r.Run(cmd); err != nil {
+			return fmt.Errorf("ionic prepare command %s failed, error: %w", cmd.PrintableCommandArgs(), err)
 		}
+		timer.leave()
 	}
 
-	buildStart := time.Now()
+	rules := cordovaOutputRules(workDir, platforms, configs.Target, configs.Configuration, isAAB)
+	if isCapacitor {
+		rules = capacitorOutputRules(workDir, platforms, configs.Target, configs.Configuration, isAAB)
+	}
+	discoverer := discovery.New(rules...)
+	if err := discoverer.Snapshot(); err != nil {
+		return fmt.Errorf("Failed to snapshot the existing outputs, error: %s", err)
+	}
+
+	startPhase(phases, phaseBuild)
+	var buildErr error
 	{
 		// build
 		var options []string
 		if configs.Options != "" {
 			opts, err := shellquote.Split(configs.Options)
 			if err != nil {
-				fail("Failed to shell split Options (%s), error: %s", configs.Options, err)
+				return fmt.Errorf("Failed to shell split Options (%s), error: %s", configs.Options, err)
 			}
 			options = opts
 		}
+		platformOptions := map[string][]string{}
+		for platform, value := range map[string]string{"ios": configs.IOSOptions, "android": configs.AndroidOptions} {
+			if value == "" {
+				continue
+			}
+			opts, err := shellquote.Split(value)
+			if err != nil {
+				return fmt.Errorf("Failed to shell split %s options (%s), error: %s", platform, value, err)
+			}
+			platformOptions[platform] = opts
+		}
 
-		for _, platform := range platforms {
-			cmdArgs := buildIonicCommandArgs(ionicMajorVersion, configs.Configuration, configs.Target, configs.BuildConfig, platform, isAAB, options)
+		if isCapacitor {
+			buildErr = buildCapacitor(r, workDir, configs, platforms, parallel, isAAB, options, platformOptions, phases.Create, timer)
+		} else {
+			if parallel {
+				if configs.RunPrepare {
+					// the platforms' web assets are built once (by the first prepare), instead of by every concurrent ionic cordova build into the same www dir
+					timer.enter(timingWebBuild)
+					for i, platform := range platforms {
+						prepareOptions := splitOptionGroups(options)[0]
+						if i > 0 {
+							prepareOptions = []string{"--no-build"}
+						}
+						cmd := ionic.PlatformPrepareCommand(platform, prepareOptions)
+						cmd.SetStdout(os.Stdout).SetStderr(os.Stderr)
+
+						log.Donef("$ %s", cmd.PrintableCommandArgs())
+
+						if err := r.Run(cmd); err != nil {
+							return fmt.Errorf("ionic prepare command %s failed, error: %w", cmd.PrintableCommandArgs(), err)
+						}
+					}
+				} else {
+					log.Warnf("The web assets are not built, as ionic prepare is disabled, the platforms are built concurrently from the existing web assets (like the ones of the ionic-prepare step)")
+				}
 
-			cmd := command.New("ionic", cmdArgs...)
-			cmd.SetStdout(os.Stdout).SetStderr(os.Stderr).SetStdin(strings.NewReader("y"))
+				for _, platform := range platforms {
+					if ionicArgs := splitOptionGroups(platformOptions[platform])[0]; len(ionicArgs) > 0 {
+						log.Warnf("The %s options of the web build (%s) are ignored, the web assets are built once for every platform", platform, strings.Join(ionicArgs, " "))
+					}
+				}
+			}
 
-			log.Donef("$ %s", cmd.PrintableCommandArgs())
+			buildErr = runPlatformBuilds(r, platforms, parallel, configs.ContinueOnFailure, os.Stdout, phases.Create, timer, func(r runner.Runner, platform string) error {
+				buildOptions := platformOptions[platform]
+				if parallel {
+					// --no-build is an ionic option, so it belongs to the first option group
+					buildOptions = append([]string{"--no-build"}, buildOptions...)
+				}
+				cmdArgs := buildIonicCommandArgs(ionicMajorVersion, configs.Configuration, configs.Target, configs.BuildConfig, platform, isAAB, options, buildOptions)
 
-			if err := cmd.Run(); err != nil {
-				fail("command failed, error: %s", err)
-			}
+				cmd := runner.New("ionic", cmdArgs...)
+				cmd.SetStdout(os.Stdout).SetStderr(os.Stderr).SetStdin(strings.NewReader("y"))
+
+				log.Donef("$ %s", cmd.PrintableCommandArgs())
+
+				if err := r.Run(cmd); err != nil {
+					return fmt.Errorf("command failed, error: %w", err)
+				}
+				return nil
+			})
 		}
 	}
 
+	if err := phases.Stop(); err != nil {
+		log.Warnf("Failed to close the build log file, error: %s", err)
+	}
+
+	statuses := map[string]string{}
+	for _, platform := range platforms {
+		statuses[platform] = platformStatusSucceeded
+	}
+	var buildFailure *platformBuildFailure
+	if buildErr != nil {
+		if !errors.As(buildErr, &buildFailure) {
+			return buildErr
+		}
+		statuses = buildFailure.statuses
+	}
+	if err := exportPlatformStatuses(r, platforms, statuses); err != nil {
+		return fmt.Errorf("Failed to export the platform build statuses, error: %s", err)
+	}
+	if buildFailure != nil {
+		succeeded := buildFailure.succeeded()
+		if !configs.ContinueOnFailure || len(succeeded) == 0 {
+			return buildFailure
+		}
+		log.Warnf("%s", buildFailure)
+		log.Warnf("Collecting the outputs of the succeeded platforms: %s", strings.Join(succeeded, ", "))
+		// only the outputs of the succeeded platforms are collected and required
+		platforms = succeeded
+	}
+
 	// collect outputs
-	var ipas, dsyms, apps []string
+	timer.enter(timingArtifactCollection)
+	log.Debugf("Output directories: %s", strings.Join(discoverer.Dirs(), ", "))
+	outputs, err := discoverer.Outputs()
+	if err != nil {
+		return fmt.Errorf("Failed to find the outputs, error: %s", err)
+	}
 
-	iosOutputDir := findFirstExistingDir(getIosOutputCandidateDirsPaths(workDir, configs.Target, configs.Configuration))
-	if iosOutputDir != "" {
-		log.Donef("\n\nIOS output dir exists!\n\n")
+	var manifest artifactManifest
+	var budgetViolations []string
 
+	var ipas, dsyms, apps []string
+	if sliceutil.IsStringInSlice("ios", platforms) {
 		fmt.Println()
-		log.Infof("Collecting ios outputs from %s", iosOutputDir)
+		log.Infof("Collecting ios outputs")
 
 		// ipa
-		ipas, err = findArtifact(iosOutputDir, "ipa", buildStart)
-		if err != nil {
-			fail("Failed to find ipas in dir (%s), error: %s", iosOutputDir, err)
-		}
-
+		ipas = findOutputs(outputs, "ipa")
 		if len(ipas) > 0 {
-			if exportedPth, _, err := moveAndExportOutputs(ipas, configs.DeployDir, ipaPathEnvKey, apkPathListEnvKey); err != nil {
-				fail("Failed to export ipas, error: %s", err)
+			if exportedPth, exportedPaths, err := moveAndExportOutputs(r, &manifest, namer, ipas, configs.DeployDir, ipaPathEnvKey, ipaPathListEnvKey); err != nil {
+				return fmt.Errorf("Failed to export ipas, error: %s", err)
 			} else if exportedPth != "" {
 				log.Donef("The ipa path is now available in the Environment Variable: %s (value: %s)", ipaPathEnvKey, exportedPth)
+				log.Donef("The ipa paths are now available in the Environment Variable: %s (value: %s)", ipaPathListEnvKey, strings.Join(exportedPaths, "|"))
+
+				buildConfig, err := readBuildConfig(configs.BuildConfig)
+				if err != nil {
+					log.Warnf("Failed to read the build config, error: %s", err)
+				}
+				inspectedIPAs := inspectIPAs(&manifest, exportedPaths, buildConfig.IOS[configs.Configuration], time.Now())
+				if ipa, ok := inspectedIPAs[exportedPth]; ok {
+					if err := exportIOSArtifact(r, ipa); err != nil {
+						return fmt.Errorf("Failed to export the ipa details, error: %s", err)
+					}
+				}
+
+				budgetViolations = append(budgetViolations, budgets.violations(reportSizes(&manifest, exportedPaths), exportedPaths)...)
 			}
 		}
 		// ---
 
 		// dsym
-		dsyms, err = findArtifact(iosOutputDir, "dSYM", buildStart)
-		if err != nil {
-			fail("Failed to find dSYMs in dir (%s), error: %s", iosOutputDir, err)
-		}
-
+		dsyms = findOutputs(outputs, "dSYM")
 		if len(dsyms) > 0 {
-			if exportedPth, _, err := moveAndExportOutputs(dsyms, configs.DeployDir, dsymDirPathEnvKey, apkPathListEnvKey); err != nil {
-				fail("Failed to export dsyms, error: %s", err)
+			if exportedPth, exportedPaths, err := moveAndExportOutputs(r, &manifest, namer, dsyms, configs.DeployDir, dsymDirPathEnvKey, ""); err != nil {
+				return fmt.Errorf("Failed to export dsyms, error: %s", err)
 			} else if exportedPth != "" {
 				log.Donef("The dsym dir path is now available in the Environment Variable: %s (value: %s)", dsymDirPathEnvKey, exportedPth)
 
-				zippedExportedPth := exportedPth + ".zip"
-				if err := ziputil.ZipDir(exportedPth, zippedExportedPth, false); err != nil {
-					fail("Failed to zip dsym dir (%s), error: %s", exportedPth, err)
-				}
-
-				if err := tools.ExportEnvironmentWithEnvman(dsymZipPathEnvKey, zippedExportedPth); err != nil {
-					fail("Failed to export dsym.zip (%s), error: %s", zippedExportedPth, err)
+				zippedPaths, err := zipAndExportDirs(r, &manifest, exportedPaths, dsymZipPathEnvKey, dsymZipPathListEnvKey)
+				if err != nil {
+					return fmt.Errorf("Failed to export dsym.zip, error: %s", err)
 				}
 
-				log.Donef("The dsym.zip path is now available in the Environment Variable: %s (value: %s)", dsymZipPathEnvKey, zippedExportedPth)
+				log.Donef("The dsym.zip path is now available in the Environment Variable: %s (value: %s)", dsymZipPathEnvKey, zippedPaths[len(zippedPaths)-1])
+				log.Donef("The dsym.zip paths are now available in the Environment Variable: %s (value: %s)", dsymZipPathListEnvKey, strings.Join(zippedPaths, "|"))
 			}
 		}
 		// --
 
 		// app
-		apps, err = findArtifact(iosOutputDir, "app", buildStart)
-		if err != nil {
-			fail("Failed to find apps in dir (%s), error: %s", iosOutputDir, err)
-		}
-
+		apps = findOutputs(outputs, "app")
 		if len(apps) > 0 {
-			if exportedPth, _, err := moveAndExportOutputs(apps, configs.DeployDir, appDirPathEnvKey, apkPathListEnvKey); err != nil {
+			if exportedPth, exportedPaths, err := moveAndExportOutputs(r, &manifest, namer, apps, configs.DeployDir, appDirPathEnvKey, ""); err != nil {
 				log.Warnf("Failed to export apps, error: %s", err)
 			} else if exportedPth != "" {
 				log.Donef("The app dir path is now available in the Environment Variable: %s (value: %s)", appDirPathEnvKey, exportedPth)
 
-				zippedExportedPth := exportedPth + ".zip"
-				if err := ziputil.ZipDir(exportedPth, zippedExportedPth, false); err != nil {
-					fail("Failed to zip app dir (%s), error: %s", exportedPth, err)
+				zippedPaths, err := zipAndExportDirs(r, &manifest, exportedPaths, appZipPathEnvKey, appZipPathListEnvKey)
+				if err != nil {
+					return fmt.Errorf("Failed to export app.zip, error: %s", err)
 				}
 
-				if err := tools.ExportEnvironmentWithEnvman(appZipPathEnvKey, zippedExportedPth); err != nil {
-					fail("Failed to export app.zip (%s), error: %s", zippedExportedPth, err)
-				}
-
-				log.Donef("The app.zip path is now available in the Environment Variable: %s (value: %s)", appZipPathEnvKey, zippedExportedPth)
+				log.Donef("The app.zip path is now available in the Environment Variable: %s (value: %s)", appZipPathEnvKey, zippedPaths[len(zippedPaths)-1])
+				log.Donef("The app.zip paths are now available in the Environment Variable: %s (value: %s)", appZipPathListEnvKey, strings.Join(zippedPaths, "|"))
 			}
 		}
 		// ---
 	}
-	// else: ios output directory not exists and ios selected as platform
 
-	var distPkg []string
-	androidOutputDir := filepath.Join(workDir, "platforms", "android")
-	log.Debugf("Android output directory: %s", androidOutputDir)
+	var distPkg, policyViolations []string
 	ext := "apk"
 	if isAAB {
 		ext = "aab"
 	}
-	if exist, err := pathutil.IsDirExists(androidOutputDir); err != nil {
-		fail("Failed to check if dir (%s) exist, error: %s", androidOutputDir, err)
-	} else if exist {
+	if sliceutil.IsStringInSlice("android", platforms) {
 		fmt.Println()
 		log.Infof("Collecting android outputs")
 
-		distPkg, err = findArtifact(androidOutputDir, ext, buildStart)
-		if err != nil {
-			fail("Failed to find %ss in dir (%s), error: %s", ext, androidOutputDir, err)
+		distPkg = findOutputs(outputs, ext)
+		var variants map[string]android.Variant
+		if !isAAB {
+			distPkg, variants = resolveAndroidVariants(distPkg)
 		}
-
 		if len(distPkg) > 0 {
 			pathEnvKey := apkPathEnvKey
 			if isAAB {
@@ -454,39 +717,134 @@ func main() {
 			if isAAB {
 				pathListEnvKey = aabPathListEnvKey
 			}
-			if exportedPth, exportedPaths, err := moveAndExportOutputs(distPkg, configs.DeployDir, pathEnvKey, pathListEnvKey); err != nil {
-				fail("Failed to export %ss, error: %s", ext, err)
+			if exportedPth, exportedPaths, err := moveAndExportOutputs(r, &manifest, namer, distPkg, configs.DeployDir, pathEnvKey, pathListEnvKey); err != nil {
+				return fmt.Errorf("Failed to export %ss, error: %s", ext, err)
 			} else if exportedPth != "" {
 				log.Donef("The %s path is now available in the Environment Variable: %s (value: %s)", ext, pathEnvKey, exportedPth)
 				if len(exportedPaths) > 0 {
 					log.Donef("The %s paths are now available in the Environment Variable: %s (value: %s)", ext, pathListEnvKey, strings.Join(exportedPaths, "|"))
 				}
+
+				for pth, variant := range variants {
+					manifest.setAndroidVariant(pth, variant)
+				}
+				androidManifests := readAndroidManifests(&manifest, exportedPaths)
+
+				var lastVariant *android.Variant
+				if variant, ok := variants[distPkg[len(distPkg)-1]]; ok {
+					lastVariant = &variant
+				}
+				var lastManifest *android.Manifest
+				if androidManifest, ok := androidManifests[exportedPth]; ok {
+					lastManifest = &androidManifest
+				}
+				if err := exportAndroidArtifact(r, lastVariant, lastManifest); err != nil {
+					return fmt.Errorf("Failed to export the %s details, error: %s", ext, err)
+				}
+
+				budgetViolations = append(budgetViolations, budgets.violations(reportSizes(&manifest, exportedPaths), exportedPaths)...)
+
+				if configs.AndroidPolicyCheck == policyCheckWarn || configs.AndroidPolicyCheck == policyCheckFail {
+					fmt.Println()
+					log.Infof("Checking Android policy")
+					policyViolations = checkAndroidPolicy(newAndroidPolicy(configs), configs.AndroidPolicyCheck, exportedPaths, androidManifests)
+				}
 			}
 		}
 	}
 
+	if configs.PreviousArtifacts != "" {
+		fmt.Println()
+		log.Infof("Comparing the artifacts with the previous build")
+		if diffPth, err := compareWithPrevious(configs.PreviousArtifacts, manifest, configs.DeployDir); err != nil {
+			log.Warnf("Failed to compare the artifacts with the previous build (%s), error: %s", configs.PreviousArtifacts, err)
+		} else {
+			if err := exportEnvironment(r, artifactDiffPathEnvKey, diffPth); err != nil {
+				return fmt.Errorf("Failed to export artifact diff path, error: %s", err)
+			}
+			log.Donef("The artifact diff path is now available in the Environment Variable: %s (value: %s)", artifactDiffPathEnvKey, diffPth)
+		}
+	}
+
+	checksumsPth, err := writeChecksums(manifest, configs.DeployDir)
+	if err != nil {
+		return fmt.Errorf("Failed to write artifact checksums, error: %s", err)
+	}
+	if err := exportEnvironment(r, checksumsPathEnvKey, checksumsPth); err != nil {
+		return fmt.Errorf("Failed to export checksums path, error: %s", err)
+	}
+	log.Donef("The checksums path is now available in the Environment Variable: %s (value: %s)", checksumsPathEnvKey, checksumsPth)
+
+	provenancePth, err := attestation.write(manifest, configs.DeployDir, time.Now())
+	if err != nil {
+		return fmt.Errorf("Failed to write build provenance, error: %s", err)
+	}
+	if err := exportEnvironment(r, provenancePathEnvKey, provenancePth); err != nil {
+		return fmt.Errorf("Failed to export build provenance path, error: %s", err)
+	}
+	log.Donef("The build provenance path is now available in the Environment Variable: %s (value: %s)", provenancePathEnvKey, provenancePth)
+
+	manifestPth, err := manifest.write(configs.DeployDir)
+	if err != nil {
+		return fmt.Errorf("Failed to write artifact manifest, error: %s", err)
+	}
+	if err := exportEnvironment(r, manifestPathEnvKey, manifestPth); err != nil {
+		return fmt.Errorf("Failed to export artifact manifest path, error: %s", err)
+	}
+	log.Donef("The artifact manifest path is now available in the Environment Variable: %s (value: %s)", manifestPathEnvKey, manifestPth)
+
+	if signingKey != nil {
+		fmt.Println()
+		log.Infof("Signing the artifact manifest and the checksums")
+		log.Printf("Public key: %s", base64.StdEncoding.EncodeToString(signingKey.Public().(ed25519.PublicKey)))
+		for _, pth := range []string{manifestPth, checksumsPth} {
+			signaturePth, err := signing.Sign(signingKey, pth)
+			if err != nil {
+				return fmt.Errorf("Failed to sign %s, error: %s", pth, err)
+			}
+			log.Donef("Signature written: %s", signaturePth)
+		}
+	}
+	timer.leave()
+
+	if buildFailure != nil {
+		return buildFailure
+	}
+	if len(budgetViolations) > 0 {
+		return fmt.Errorf("Size budget exceeded:\n%s", strings.Join(budgetViolations, "\n"))
+	}
+	if len(policyViolations) > 0 && configs.AndroidPolicyCheck == policyCheckFail {
+		return fmt.Errorf("Android policy check failed: %s", strings.Join(policyViolations, "; "))
+	}
+
 	// if android in platforms
 	if len(distPkg) == 0 && sliceutil.IsStringInSlice("android", platforms) {
-		fail("No %s generated", ext)
+		return fmt.Errorf("No %s generated", ext)
 	}
 	// if ios in platforms
 	if sliceutil.IsStringInSlice("ios", platforms) {
 		if len(apps) == 0 && configs.Target == "emulator" {
-			fail("no apps generated")
+			return fmt.Errorf("no apps generated")
 		}
 		if len(ipas) == 0 && configs.Target == "device" {
-			fail("no ipas generated")
+			return fmt.Errorf("no ipas generated")
 		}
 	}
 
 	if configs.UseCache {
+		timer.enter(timingCacheMarking)
 		if err := cacheNpm(workDir); err != nil {
 			log.Warnf("Failed to mark files for caching, error: %s", err)
 		}
+		timer.leave()
 	}
+
+	return nil
 }
 
-func buildIonicCommandArgs(ionicMajorVersion int, configuration string, target string, buildConfig string, platform string, isAAB bool, options []string) []string {
+// buildIonicCommandArgs returns the arguments of the ionic build command,
+// the option groups of the platform are appended to the shared option groups (see mergeOptionGroups).
+func buildIonicCommandArgs(ionicMajorVersion int, configuration string, target string, buildConfig string, platform string, isAAB bool, options []string, platformOptions []string) []string {
 	var cmdArgs []string
 	if ionicMajorVersion > 2 {
 		cmdArgs = append(cmdArgs, "cordova")
@@ -508,19 +866,7 @@ func buildIonicCommandArgs(ionicMajorVersion int, configuration string, target s
 		cmdArgs = append(cmdArgs, "--buildConfig", buildConfig)
 	}
 
-	// Ionic CLI uses -- to indicate further parameters are passed to Cordova CLI
-	// Cordova CLI uses -- to indicate further parameters are platform arguments
-
-	groupArgs := map[int][]string{0: []string{}, 1: []string{}, 2: []string{}}
-
-	group := 0
-	for _, option := range options {
-		if option == "--" {
-			group++
-			continue
-		}
-		groupArgs[group] = append(groupArgs[group], option)
-	}
+	groupArgs := mergeOptionGroups(splitOptionGroups(options), splitOptionGroups(platformOptions))
 
 	if platform == "android" {
 		if isAAB {
@@ -547,3 +893,34 @@ func buildIonicCommandArgs(ionicMajorVersion int, configuration string, target s
 
 	return cmdArgs
 }
+
+// splitOptionGroups splits the options at the -- separators.
+// Ionic CLI uses -- to indicate further parameters are passed to Cordova CLI
+// Cordova CLI uses -- to indicate further parameters are platform arguments
+func splitOptionGroups(options []string) map[int][]string {
+	groupArgs := map[int][]string{0: []string{}, 1: []string{}, 2: []string{}}
+
+	group := 0
+	for _, option := range options {
+		if option == "--" {
+			group++
+			continue
+		}
+		groupArgs[group] = append(groupArgs[group], option)
+	}
+
+	return groupArgs
+}
+
+// mergeOptionGroups appends the platform specific option groups to the shared ones,
+// so that the ionic, the cordova and the platform arguments of both end up in the right group.
+func mergeOptionGroups(shared, platform map[int][]string) map[int][]string {
+	merged := map[int][]string{}
+	for group, args := range shared {
+		merged[group] = append(merged[group], args...)
+	}
+	for group, args := range platform {
+		merged[group] = append(merged[group], args...)
+	}
+	return merged
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/bitrise-io/go-utils/sliceutil"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_buildIonicCommandArgs(t *testing.T) {
 	type args struct {
-		isAAB   bool
-		options []string
+		isAAB           bool
+		options         []string
+		platformOptions []string
 	}
 	tests := []struct {
 		name string
@@ -39,12 +48,450 @@ func Test_buildIonicCommandArgs(t *testing.T) {
 			isAAB:   true,
 			options: []string{"foo", "bar", "--", "baz", "--", "qux"},
 		}, want: []string{"cordova", "build", "--release", "--device", "android", "--buildConfig", "/foo/bar/baz/qux", "foo", "bar", "--", "baz", "--", "qux", "--packageType=bundle"}},
+		{name: "PlatformOptions & NoAAB", args: args{
+			isAAB:           false,
+			platformOptions: []string{"--", "--", "--gradleArg=-PcdvMinSdkVersion=24"},
+		}, want: []string{"cordova", "build", "--release", "--device", "android", "--buildConfig", "/foo/bar/baz/qux", "--", "--", "--gradleArg=-PcdvMinSdkVersion=24", "--packageType=apk"}},
+		{name: "ComplexOptions + PlatformOptions & AAB", args: args{
+			isAAB:           true,
+			options:         []string{"foo", "--", "baz", "--", "qux"},
+			platformOptions: []string{"bar", "--", "--", "--gradleArg=-PcdvMinSdkVersion=24"},
+		}, want: []string{"cordova", "build", "--release", "--device", "android", "--buildConfig", "/foo/bar/baz/qux", "foo", "bar", "--", "baz", "--", "qux", "--gradleArg=-PcdvMinSdkVersion=24", "--packageType=bundle"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := buildIonicCommandArgs(3, "release", "device", "/foo/bar/baz/qux", "android", tt.args.isAAB, tt.args.options); !reflect.DeepEqual(got, tt.want) {
+			if got := buildIonicCommandArgs(3, "release", "device", "/foo/bar/baz/qux", "android", tt.args.isAAB, tt.args.options, tt.args.platformOptions); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("buildIonicCommandArgs() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_archive(t *testing.T) {
+	tests := []struct {
+		name           string
+		platform       string
+		androidAppType string
+		cordovaVersion string
+		// existingFiles are in the project before the build, like the outputs of a previous build
+		existingFiles map[string]string
+		wantCommands  []string
+		// wantEnvs are the exported paths relative to the deploy dir
+		wantEnvs map[string]string
+		// wantValueEnvs are the other exported values
+		wantValueEnvs map[string]string
+	}{
+		{
+			name:           "ios and android with aab",
+			platform:       "ios,android",
+			androidAppType: "aab",
+			cordovaVersion: "12.0.0",
+			wantCommands: []string{
+				"cordova -v",
+				"ionic -v",
+				"ionic cordova prepare --no-build",
+				"ionic cordova build --release --device android -- -- --packageType=bundle",
+				"ionic cordova build --release --device ios",
+				"git rev-parse HEAD",
+			},
+			wantEnvs: map[string]string{
+				"BITRISE_IPA_PATH":      "app.ipa",
+				"BITRISE_IPA_PATH_LIST": "app.ipa",
+				"BITRISE_AAB_PATH":      "app-release.aab",
+				"BITRISE_AAB_PATH_LIST": "app-release.aab",
+
+				"BITRISE_IONIC_ARTIFACT_MANIFEST_PATH": "ionic-archive-manifest.json",
+				"BITRISE_IONIC_CHECKSUMS_PATH":         "SHA256SUMS",
+				"BITRISE_IONIC_PROVENANCE_PATH":        "ionic-archive-provenance.intoto.json",
+				"BITRISE_IONIC_TIMINGS_PATH":           "ionic-archive-timings.json",
+			},
+			wantValueEnvs: map[string]string{
+				"BITRISE_IONIC_ANDROID_STATUS": "succeeded",
+				"BITRISE_IONIC_IOS_STATUS":     "succeeded",
+			},
+		},
+		{
+			name:           "aab falls back to apk with old cordova",
+			platform:       "android",
+			androidAppType: "aab",
+			cordovaVersion: "8.0.0",
+			wantCommands: []string{
+				"cordova -v",
+				"ionic -v",
+				"ionic cordova prepare --no-build",
+				"ionic cordova build --release --device android -- -- --packageType=apk",
+				"git rev-parse HEAD",
+			},
+			wantEnvs: map[string]string{
+				"BITRISE_APK_PATH":      "app-release.apk",
+				"BITRISE_APK_PATH_LIST": "app-release.apk",
+
+				"BITRISE_IONIC_ARTIFACT_MANIFEST_PATH": "ionic-archive-manifest.json",
+				"BITRISE_IONIC_CHECKSUMS_PATH":         "SHA256SUMS",
+				"BITRISE_IONIC_PROVENANCE_PATH":        "ionic-archive-provenance.intoto.json",
+				"BITRISE_IONIC_TIMINGS_PATH":           "ionic-archive-timings.json",
+			},
+			wantValueEnvs: map[string]string{
+				"BITRISE_IONIC_ANDROID_STATUS": "succeeded",
+			},
+		},
+		{
+			name:           "apk with gradle output metadata",
+			platform:       "android",
+			androidAppType: "apk",
+			cordovaVersion: "12.0.0",
+			existingFiles: map[string]string{
+				"platforms/android/app/build/outputs/apk/release/output-metadata.json": `{
+  "applicationId": "io.ionic.starter",
+  "variantName": "release",
+  "elements": [{"type": "SINGLE", "filters": [], "versionCode": 10203, "versionName": "1.2.3", "outputFile": "app-release.apk"}]
+}`,
+			},
+			wantCommands: []string{
+				"cordova -v",
+				"ionic -v",
+				"ionic cordova prepare --no-build",
+				"ionic cordova build --release --device android -- -- --packageType=apk",
+				"git rev-parse HEAD",
+			},
+			wantEnvs: map[string]string{
+				"BITRISE_APK_PATH":      "app-release.apk",
+				"BITRISE_APK_PATH_LIST": "app-release.apk",
+
+				"BITRISE_IONIC_ARTIFACT_MANIFEST_PATH": "ionic-archive-manifest.json",
+				"BITRISE_IONIC_CHECKSUMS_PATH":         "SHA256SUMS",
+				"BITRISE_IONIC_PROVENANCE_PATH":        "ionic-archive-provenance.intoto.json",
+				"BITRISE_IONIC_TIMINGS_PATH":           "ionic-archive-timings.json",
+			},
+			wantValueEnvs: map[string]string{
+				"BITRISE_ANDROID_VARIANT":      "release",
+				"BITRISE_ANDROID_VERSION_CODE": "10203",
+				"BITRISE_ANDROID_VERSION_NAME": "1.2.3",
+				"BITRISE_ANDROID_ABI_FILTERS":  "",
+				"BITRISE_IONIC_ANDROID_STATUS": "succeeded",
+			},
+		},
+		{
+			name:           "outputs of a previous build are not exported",
+			platform:       "ios,android",
+			androidAppType: "apk",
+			cordovaVersion: "12.0.0",
+			existingFiles: map[string]string{
+				"platforms/ios/build/Release-iphoneos/previous.ipa":                     "previous ipa",
+				"platforms/android/app/build/outputs/apk/release/app-release.apk":       "previous apk",
+				"platforms/android/app/build/outputs/apk/debug/app-debug.apk":           "previous debug apk",
+				"platforms/android/app/build/intermediates/apk/release/app-release.apk": "intermediate apk",
+			},
+			wantCommands: []string{
+				"cordova -v",
+				"ionic -v",
+				"ionic cordova prepare --no-build",
+				"ionic cordova build --release --device android -- -- --packageType=apk",
+				"ionic cordova build --release --device ios",
+				"git rev-parse HEAD",
+			},
+			wantEnvs: map[string]string{
+				"BITRISE_IPA_PATH":      "app.ipa",
+				"BITRISE_IPA_PATH_LIST": "app.ipa",
+				"BITRISE_APK_PATH":      "app-release.apk",
+				"BITRISE_APK_PATH_LIST": "app-release.apk",
+
+				"BITRISE_IONIC_ARTIFACT_MANIFEST_PATH": "ionic-archive-manifest.json",
+				"BITRISE_IONIC_CHECKSUMS_PATH":         "SHA256SUMS",
+				"BITRISE_IONIC_PROVENANCE_PATH":        "ionic-archive-provenance.intoto.json",
+				"BITRISE_IONIC_TIMINGS_PATH":           "ionic-archive-timings.json",
+			},
+			wantValueEnvs: map[string]string{
+				"BITRISE_IONIC_ANDROID_STATUS": "succeeded",
+				"BITRISE_IONIC_IOS_STATUS":     "succeeded",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			deployDir := t.TempDir()
+			writeFiles(t, workDir, map[string]string{
+				"ionic.config.json": `{"type": "angular", "integrations": {"cordova": {}}}`,
+				"config.xml":        `<widget id="io.ionic.starter"></widget>`,
+				"package.json":      `{}`,
+			})
+			writeFiles(t, workDir, tt.existingFiles)
+
+			r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+				switch {
+				case cmd.String() == "ionic -v":
+					return "6.20.1", nil
+				case cmd.String() == "cordova -v":
+					return tt.cordovaVersion, nil
+				case strings.HasPrefix(cmd.String(), "ionic cordova build") && sliceutil.IsStringInSlice("android", cmd.Args):
+					if sliceutil.IsStringInSlice("--packageType=bundle", cmd.Args) {
+						writeFiles(t, workDir, map[string]string{"platforms/android/app/build/outputs/bundle/release/app-release.aab": "aab"})
+					} else {
+						writeFiles(t, workDir, map[string]string{"platforms/android/app/build/outputs/apk/release/app-release.apk": "apk"})
+					}
+				case strings.HasPrefix(cmd.String(), "ionic cordova build") && sliceutil.IsStringInSlice("ios", cmd.Args):
+					writeFiles(t, workDir, map[string]string{"platforms/ios/build/Release-iphoneos/app.ipa": "ipa"})
+				}
+				return "", nil
+			})
+
+			configs := config{
+				Platform:       tt.platform,
+				Integration:    "auto",
+				Configuration:  "release",
+				Target:         "device",
+				RunPrepare:     true,
+				WorkDir:        workDir,
+				DeployDir:      deployDir,
+				AndroidAppType: tt.androidAppType,
+			}
+			require.NoError(t, archive(workDir, configs, r))
+
+			var commands []string
+			for _, record := range r.Records() {
+				if record.Name != "envman" {
+					commands = append(commands, record.String())
+				}
+			}
+			require.Equal(t, tt.wantCommands, commands)
+
+			wantEnvs := map[string]string{}
+			for key, value := range tt.wantEnvs {
+				wantEnvs[key] = filepath.Join(deployDir, value)
+			}
+			for key, value := range tt.wantValueEnvs {
+				wantEnvs[key] = value
+			}
+			require.Equal(t, wantEnvs, withoutPhaseLogs(t, deployDir, []string{"install", "prepare", "build"}, exportedEnvs(r.Records())))
+		})
+	}
+}
+
+func Test_archive_buildFailure(t *testing.T) {
+	workDir := t.TempDir()
+	writeFiles(t, workDir, map[string]string{
+		"config.xml":   `<widget id="io.ionic.starter"></widget>`,
+		"package.json": `{}`,
+	})
+
+	r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		switch cmd.String() {
+		case "ionic -v":
+			return "6.20.1", nil
+		case "cordova -v":
+			return "12.0.0", nil
+		}
+		if strings.HasPrefix(cmd.String(), "ionic cordova build") {
+			return "", errors.New("exit status 1")
+		}
+		return "", nil
+	})
+
+	deployDir := t.TempDir()
+	configs := config{Platform: "ios,android", Integration: "auto", Configuration: "release", Target: "device", WorkDir: workDir, DeployDir: deployDir, AndroidAppType: "apk"}
+	require.EqualError(t, archive(workDir, configs, r), "1 of 2 platform builds failed, android: command failed, error: exit status 1")
+	require.Equal(t, []string{
+		"cordova -v",
+		"ionic -v",
+		"ionic cordova build --release --device android -- -- --packageType=apk",
+	}, commandStrings(r.Records()))
+	require.Equal(t, map[string]string{
+		"BITRISE_IONIC_TIMINGS_PATH": filepath.Join(deployDir, "ionic-archive-timings.json"),
+
+		"BITRISE_IONIC_ANDROID_STATUS": "failed",
+		"BITRISE_IONIC_IOS_STATUS":     "skipped",
+	}, withoutPhaseLogs(t, deployDir, []string{"install", "build"}, exportedEnvs(r.Records())))
+}
+
+func Test_archive_continueOnFailure(t *testing.T) {
+	workDir := t.TempDir()
+	deployDir := t.TempDir()
+	writeFiles(t, workDir, map[string]string{
+		"config.xml":   `<widget id="io.ionic.starter"></widget>`,
+		"package.json": `{}`,
+	})
+
+	r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		switch {
+		case cmd.String() == "ionic -v":
+			return "6.20.1", nil
+		case cmd.String() == "cordova -v":
+			return "12.0.0", nil
+		case strings.HasPrefix(cmd.String(), "ionic cordova build") && sliceutil.IsStringInSlice("android", cmd.Args):
+			return "", errors.New("exit status 1")
+		case strings.HasPrefix(cmd.String(), "ionic cordova build") && sliceutil.IsStringInSlice("ios", cmd.Args):
+			writeFiles(t, workDir, map[string]string{"platforms/ios/build/Release-iphoneos/app.ipa": "ipa"})
+		}
+		return "", nil
+	})
+
+	configs := config{Platform: "ios,android", Integration: "auto", Configuration: "release", Target: "device", WorkDir: workDir, DeployDir: deployDir, AndroidAppType: "apk", ContinueOnFailure: true}
+	require.EqualError(t, archive(workDir, configs, r), "1 of 2 platform builds failed, android: command failed, error: exit status 1")
+	require.Equal(t, []string{
+		"cordova -v",
+		"ionic -v",
+		"ionic cordova build --release --device android -- -- --packageType=apk",
+		"ionic cordova build --release --device ios",
+		"git rev-parse HEAD",
+	}, commandStrings(r.Records()))
+
+	// the artifacts of the succeeded platform are exported
+	require.Equal(t, map[string]string{
+		"BITRISE_IPA_PATH":      filepath.Join(deployDir, "app.ipa"),
+		"BITRISE_IPA_PATH_LIST": filepath.Join(deployDir, "app.ipa"),
+
+		"BITRISE_IONIC_ARTIFACT_MANIFEST_PATH": filepath.Join(deployDir, "ionic-archive-manifest.json"),
+		"BITRISE_IONIC_CHECKSUMS_PATH":         filepath.Join(deployDir, "SHA256SUMS"),
+		"BITRISE_IONIC_PROVENANCE_PATH":        filepath.Join(deployDir, "ionic-archive-provenance.intoto.json"),
+		"BITRISE_IONIC_TIMINGS_PATH":           filepath.Join(deployDir, "ionic-archive-timings.json"),
+
+		"BITRISE_IONIC_ANDROID_STATUS": "failed",
+		"BITRISE_IONIC_IOS_STATUS":     "succeeded",
+	}, withoutPhaseLogs(t, deployDir, []string{"install", "build"}, exportedEnvs(r.Records())))
+}
+
+func Test_zipAndExportDirs(t *testing.T) {
+	deployDir := t.TempDir()
+	writeFiles(t, deployDir, map[string]string{
+		"App.app/App":             "app",
+		"Widget.appex.app/Widget": "widget",
+	})
+	dirs := []string{filepath.Join(deployDir, "App.app"), filepath.Join(deployDir, "Widget.appex.app")}
+
+	var manifest artifactManifest
+	for _, dir := range dirs {
+		require.NoError(t, manifest.add(dir, dir))
+	}
+
+	r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		if cmd.Name == "/usr/bin/zip" {
+			writeFiles(t, "/", map[string]string{cmd.Args[1]: "zip"})
+		}
+		return "", nil
+	})
+
+	zippedPaths, err := zipAndExportDirs(r, &manifest, dirs, appZipPathEnvKey, appZipPathListEnvKey)
+	require.NoError(t, err)
+	require.Equal(t, []string{dirs[0] + ".zip", dirs[1] + ".zip"}, zippedPaths)
+	require.Equal(t, map[string]string{
+		"BITRISE_APP_PATH":      dirs[1] + ".zip",
+		"BITRISE_APP_PATH_LIST": dirs[0] + ".zip|" + dirs[1] + ".zip",
+	}, exportedEnvs(r.Records()))
+
+	for i, artifact := range manifest.Artifacts {
+		require.Equal(t, zippedPaths[i], artifact.Zip.Path)
+		require.Equal(t, int64(3), artifact.Zip.Size)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		pth := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(pth), 0755))
+		require.NoError(t, os.WriteFile(pth, []byte(content), 0600))
+	}
+}
+
+// withoutPhaseLogs checks that the log files of the phases are written into the deploy dir and exported,
+// and returns the other exported envs, as the log file names include the start time.
+func withoutPhaseLogs(t *testing.T, deployDir string, wantPhases []string, envs map[string]string) map[string]string {
+	var phases []string
+	others := map[string]string{}
+	for key, value := range envs {
+		if !strings.HasSuffix(key, "_LOG_PATH") {
+			others[key] = value
+			continue
+		}
+		phase := strings.ToLower(strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(key, "BITRISE_IONIC_"), "_LOG_PATH"), "_", "-"))
+		phases = append(phases, phase)
+		require.Equal(t, deployDir, filepath.Dir(value))
+		require.True(t, strings.HasPrefix(filepath.Base(value), "ionic-"+phase+"-"), value)
+		require.FileExists(t, value)
+	}
+	require.ElementsMatch(t, wantPhases, phases)
+	return others
+}
+
+// commandStrings returns the recorded commands, except the env exports
+func commandStrings(records []runner.Record) []string {
+	var commands []string
+	for _, record := range records {
+		if record.Name != "envman" {
+			commands = append(commands, record.String())
+		}
+	}
+	return commands
+}
+
+func exportedEnvs(records []runner.Record) map[string]string {
+	envs := map[string]string{}
+	for _, record := range records {
+		if record.Name == "envman" {
+			envs[record.Args[2]] = record.Input
+		}
+	}
+	return envs
+}
+
+func Test_archive_capacitor(t *testing.T) {
+	workDir := t.TempDir()
+	deployDir := t.TempDir()
+	writeFiles(t, workDir, map[string]string{
+		"capacitor.config.json": `{"appId": "io.ionic.starter"}`,
+		"package.json":          `{"dependencies": {"@capacitor/core": "5.0.0", "react": "18.0.0"}}`,
+		"android/gradlew":       "",
+		"ios/App/App.xcworkspace/contents.xcworkspacedata": "",
+	})
+
+	r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		switch {
+		case cmd.String() == "ionic -v":
+			return "7.1.1", nil
+		case strings.HasPrefix(cmd.String(), "./gradlew assembleDebug"):
+			writeFiles(t, workDir, map[string]string{"android/app/build/outputs/apk/debug/app-debug.apk": "apk"})
+		case strings.HasPrefix(cmd.String(), "xcodebuild build"):
+			writeFiles(t, workDir, map[string]string{"ios/build/DerivedData/Build/Products/Debug-iphonesimulator/App.app/App": "app"})
+		case cmd.Name == "/usr/bin/zip":
+			writeFiles(t, "/", map[string]string{cmd.Args[1]: "zip"})
+		}
+		return "", nil
+	})
+
+	configs := config{Platform: "ios,android", Integration: "auto", Configuration: "debug", Target: "emulator", RunPrepare: true, WorkDir: workDir, DeployDir: deployDir, AndroidAppType: "apk",
+		AndroidOptions: "-- --deployment -- --stacktrace", IOSOptions: "-- -- -quiet"}
+	require.NoError(t, archive(workDir, configs, r))
+
+	var commands []string
+	for _, record := range r.Records() {
+		if record.Name != "envman" {
+			commands = append(commands, record.String())
+		}
+	}
+	require.Equal(t, []string{
+		"ionic -v",
+		"ionic build",
+		"npx cap sync android --deployment",
+		"./gradlew assembleDebug --stacktrace",
+		"npx cap sync ios",
+		"xcodebuild build -workspace " + filepath.Join(workDir, "ios/App/App.xcworkspace") + " -scheme App -configuration Debug -sdk iphonesimulator -derivedDataPath " + filepath.Join(workDir, "ios/build/DerivedData") + " CODE_SIGNING_ALLOWED=NO -quiet",
+		"/usr/bin/zip -rTy " + filepath.Join(deployDir, "App.app.zip") + " App.app",
+		"git rev-parse HEAD",
+	}, commands)
+
+	require.Equal(t, map[string]string{
+		"BITRISE_APP_DIR_PATH":  filepath.Join(deployDir, "App.app"),
+		"BITRISE_APP_PATH":      filepath.Join(deployDir, "App.app.zip"),
+		"BITRISE_APP_PATH_LIST": filepath.Join(deployDir, "App.app.zip"),
+		"BITRISE_APK_PATH":      filepath.Join(deployDir, "app-debug.apk"),
+		"BITRISE_APK_PATH_LIST": filepath.Join(deployDir, "app-debug.apk"),
+
+		"BITRISE_IONIC_ARTIFACT_MANIFEST_PATH": filepath.Join(deployDir, "ionic-archive-manifest.json"),
+		"BITRISE_IONIC_CHECKSUMS_PATH":         filepath.Join(deployDir, "SHA256SUMS"),
+		"BITRISE_IONIC_PROVENANCE_PATH":        filepath.Join(deployDir, "ionic-archive-provenance.intoto.json"),
+		"BITRISE_IONIC_TIMINGS_PATH":           filepath.Join(deployDir, "ionic-archive-timings.json"),
+
+		"BITRISE_IONIC_ANDROID_STATUS": "succeeded",
+		"BITRISE_IONIC_IOS_STATUS":     "succeeded",
+	}, withoutPhaseLogs(t, deployDir, []string{"install", "build"}, exportedEnvs(r.Records())))
+}
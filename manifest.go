@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/android"
+	"github.com/bitrise-steplib/steps-ionic-archive/breakdown"
+	"github.com/bitrise-steplib/steps-ionic-archive/digest"
+	"github.com/bitrise-steplib/steps-ionic-archive/ios"
+)
+
+const (
+	manifestPathEnvKey = "BITRISE_IONIC_ARTIFACT_MANIFEST_PATH"
+	manifestFileName   = "ionic-archive-manifest.json"
+)
+
+// manifestArtifact describes an exported artifact.
+// The size and the digest of a directory artifact (.app, .dSYM) are calculated over the files in the directory (see digest.Dir).
+type manifestArtifact struct {
+	Platform     string `json:"platform"`
+	Type         string `json:"type"`
+	SourcePath   string `json:"source_path"`
+	DeployedPath string `json:"deployed_path"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+	// Zip describes the zipped copy of a directory artifact
+	Zip *manifestZip `json:"zip,omitempty"`
+	// AndroidVariant describes an APK listed in the Gradle output metadata
+	AndroidVariant *manifestAndroidVariant `json:"android_variant,omitempty"`
+	// AndroidManifest describes the AndroidManifest.xml of an APK or AAB
+	AndroidManifest *manifestAndroidManifest `json:"android_manifest,omitempty"`
+	// IOSApp describes the Info.plist and the embedded provisioning profile of an IPA
+	IOSApp *manifestIOSApp `json:"ios_app,omitempty"`
+	// SizeBreakdown splits the size of an ipa, apk or aab by the category of its entries
+	SizeBreakdown []breakdown.Category `json:"size_breakdown,omitempty"`
+	// NativeLibs and WebAssets list the native libraries (frameworks of an ipa) and the web app files of an ipa, apk or aab
+	NativeLibs []string `json:"native_libs,omitempty"`
+	WebAssets  []string `json:"web_assets,omitempty"`
+}
+
+type manifestIOSApp struct {
+	BundleID    string              `json:"bundle_id"`
+	Version     string              `json:"version"`
+	BuildNumber string              `json:"build_number"`
+	Profile     *manifestIOSProfile `json:"provisioning_profile,omitempty"`
+}
+
+type manifestIOSProfile struct {
+	Name           string                 `json:"name"`
+	UUID           string                 `json:"uuid"`
+	TeamID         string                 `json:"team_id"`
+	ExportMethod   string                 `json:"export_method"`
+	ExpirationDate time.Time              `json:"expiration_date"`
+	Entitlements   map[string]interface{} `json:"entitlements,omitempty"`
+}
+
+type manifestAndroidManifest struct {
+	PackageName string   `json:"package_name"`
+	VersionCode int      `json:"version_code"`
+	VersionName string   `json:"version_name"`
+	MinSDK      int      `json:"min_sdk"`
+	TargetSDK   int      `json:"target_sdk"`
+	Permissions []string `json:"permissions,omitempty"`
+	Debuggable  bool     `json:"debuggable"`
+}
+
+type manifestAndroidVariant struct {
+	ApplicationID string   `json:"application_id"`
+	Name          string   `json:"name"`
+	VersionCode   int      `json:"version_code"`
+	VersionName   string   `json:"version_name"`
+	ABIs          []string `json:"abis,omitempty"`
+}
+
+type manifestZip struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// artifactManifest lists every exported artifact, it is written into the deploy dir
+type artifactManifest struct {
+	Artifacts []manifestArtifact `json:"artifacts"`
+}
+
+// artifactType returns the type (ipa, app, dSYM, apk, aab) and the platform of the artifact based on its extension
+func artifactType(pth string) (string, string) {
+	typ := strings.TrimPrefix(filepath.Ext(pth), ".")
+	switch typ {
+	case "ipa", "app", "dSYM":
+		return typ, "ios"
+	case "apk", "aab":
+		return typ, "android"
+	}
+	return typ, ""
+}
+
+// add records the artifact copied from the source path to the deployed path
+func (m *artifactManifest) add(sourcePth, deployedPth string) error {
+	size, sum, err := digest.Path(deployedPth)
+	if err != nil {
+		return fmt.Errorf("failed to calculate the digest of %s: %s", deployedPth, err)
+	}
+
+	typ, platform := artifactType(deployedPth)
+	m.Artifacts = append(m.Artifacts, manifestArtifact{
+		Platform:     platform,
+		Type:         typ,
+		SourcePath:   sourcePth,
+		DeployedPath: deployedPth,
+		Size:         size,
+		SHA256:       sum,
+	})
+	return nil
+}
+
+// addZip records the zipped copy of the deployed directory artifact
+func (m *artifactManifest) addZip(deployedPth, zipPth string) error {
+	for i, artifact := range m.Artifacts {
+		if artifact.DeployedPath != deployedPth {
+			continue
+		}
+
+		size, sum, err := digest.File(zipPth)
+		if err != nil {
+			return fmt.Errorf("failed to calculate the digest of %s: %s", zipPth, err)
+		}
+		m.Artifacts[i].Zip = &manifestZip{Path: zipPth, Size: size, SHA256: sum}
+		return nil
+	}
+	return fmt.Errorf("artifact not found in the manifest: %s", deployedPth)
+}
+
+// setAndroidVariant records the variant of the artifact copied from the source path
+func (m *artifactManifest) setAndroidVariant(sourcePth string, variant android.Variant) {
+	for i, artifact := range m.Artifacts {
+		if artifact.SourcePath == sourcePth {
+			m.Artifacts[i].AndroidVariant = &manifestAndroidVariant{
+				ApplicationID: variant.ApplicationID,
+				Name:          variant.Name,
+				VersionCode:   variant.VersionCode,
+				VersionName:   variant.VersionName,
+				ABIs:          variant.ABIs,
+			}
+		}
+	}
+}
+
+// setAndroidManifest records the AndroidManifest.xml of the deployed APK or AAB
+func (m *artifactManifest) setAndroidManifest(deployedPth string, androidManifest android.Manifest) {
+	for i, artifact := range m.Artifacts {
+		if artifact.DeployedPath == deployedPth {
+			m.Artifacts[i].AndroidManifest = &manifestAndroidManifest{
+				PackageName: androidManifest.PackageName,
+				VersionCode: androidManifest.VersionCode,
+				VersionName: androidManifest.VersionName,
+				MinSDK:      androidManifest.MinSDK,
+				TargetSDK:   androidManifest.TargetSDK,
+				Permissions: androidManifest.Permissions,
+				Debuggable:  androidManifest.Debuggable,
+			}
+		}
+	}
+}
+
+// setIOSApp records the Info.plist and the provisioning profile of the deployed IPA
+func (m *artifactManifest) setIOSApp(deployedPth string, ipa ios.IPA) {
+	for i, artifact := range m.Artifacts {
+		if artifact.DeployedPath != deployedPth {
+			continue
+		}
+
+		app := &manifestIOSApp{BundleID: ipa.BundleID, Version: ipa.Version, BuildNumber: ipa.BuildNumber}
+		if profile := ipa.Profile; profile != nil {
+			app.Profile = &manifestIOSProfile{
+				Name:           profile.Name,
+				UUID:           profile.UUID,
+				TeamID:         profile.TeamID,
+				ExportMethod:   profile.ExportMethod(),
+				ExpirationDate: profile.ExpirationDate,
+				Entitlements:   profile.Entitlements,
+			}
+		}
+		m.Artifacts[i].IOSApp = app
+	}
+}
+
+// setBreakdown records the size breakdown, the native libraries and the web assets of the deployed archive
+func (m *artifactManifest) setBreakdown(deployedPth string, report breakdown.Report) {
+	for i, artifact := range m.Artifacts {
+		if artifact.DeployedPath == deployedPth {
+			m.Artifacts[i].SizeBreakdown = report.Categories
+			m.Artifacts[i].NativeLibs = report.NativeLibs
+			m.Artifacts[i].WebAssets = report.WebAssets
+		}
+	}
+}
+
+// artifactFile is an exported file or the zipped copy of an exported directory with its SHA-256 digest
+type artifactFile struct {
+	path   string
+	sha256 string
+}
+
+// files returns the exported files and the zipped copies of the directories (.app, .dSYM), the directories are verified through their zipped copy
+func (m artifactManifest) files() []artifactFile {
+	var files []artifactFile
+	for _, artifact := range m.Artifacts {
+		if artifact.Zip != nil {
+			files = append(files, artifactFile{path: artifact.Zip.Path, sha256: artifact.Zip.SHA256})
+		}
+		if artifact.Type == "app" || artifact.Type == "dSYM" {
+			continue
+		}
+		files = append(files, artifactFile{path: artifact.DeployedPath, sha256: artifact.SHA256})
+	}
+	return files
+}
+
+// write writes the manifest into the dir and returns its path
+func (m artifactManifest) write(dir string) (string, error) {
+	if m.Artifacts == nil {
+		m.Artifacts = []manifestArtifact{}
+	}
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	pth := filepath.Join(dir, manifestFileName)
+	if err := os.WriteFile(pth, append(content, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
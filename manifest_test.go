@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_artifactManifest(t *testing.T) {
+	deployDir := t.TempDir()
+	writeFiles(t, deployDir, map[string]string{
+		"app-release.apk":        "apk",
+		"App.app/App":            "binary",
+		"App.app/www/index.html": "<html></html>",
+	})
+
+	var manifest artifactManifest
+	require.NoError(t, manifest.add("/workdir/platforms/android/app-release.apk", filepath.Join(deployDir, "app-release.apk")))
+	require.NoError(t, manifest.add("/workdir/platforms/ios/App.app", filepath.Join(deployDir, "App.app")))
+
+	pth, err := manifest.write(deployDir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(deployDir, "ionic-archive-manifest.json"), pth)
+
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+
+	var got artifactManifest
+	require.NoError(t, json.Unmarshal(content, &got))
+	require.Equal(t, []manifestArtifact{
+		{
+			Platform:     "android",
+			Type:         "apk",
+			SourcePath:   "/workdir/platforms/android/app-release.apk",
+			DeployedPath: filepath.Join(deployDir, "app-release.apk"),
+			Size:         3,
+			SHA256:       "dd37c2d7274f7ea982cb83390c36918fee9ce8889073c44b68cdc00bdb8c3e04",
+		},
+		{
+			Platform:     "ios",
+			Type:         "app",
+			SourcePath:   "/workdir/platforms/ios/App.app",
+			DeployedPath: filepath.Join(deployDir, "App.app"),
+			Size:         19,
+			SHA256:       got.Artifacts[1].SHA256,
+		},
+	}, got.Artifacts)
+}
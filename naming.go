@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/project"
+)
+
+const defaultArtifactNameTemplate = "{name}.{ext}"
+
+// Artifact name template placeholders
+const (
+	placeholderName    = "name"
+	placeholderAppID   = "appId"
+	placeholderVersion = "version"
+	placeholderFlavor  = "flavor"
+	placeholderABI     = "abi"
+	placeholderExt     = "ext"
+)
+
+var knownPlaceholders = []string{placeholderName, placeholderAppID, placeholderVersion, placeholderFlavor, placeholderABI, placeholderExt}
+
+// placeholderPattern matches a placeholder together with the separator in front of it,
+// the separator is dropped with an empty placeholder value (for example {flavor} of an iOS artifact), except for {ext}.
+var placeholderPattern = regexp.MustCompile(`([-_.]?)\{([^{}]*)\}`)
+
+// androidABIs are the ABI names used in the split APK paths, longer names first to avoid partial matches
+var androidABIs = []string{"universal", "armeabi-v7a", "arm64-v8a", "x86_64", "x86", "armv7", "arm64"}
+
+// artifactNamer names the artifacts copied into the deploy dir based on the artifact name template,
+// and makes sure that an artifact does not overwrite an other one exported by the step.
+type artifactNamer struct {
+	template string
+	app      project.App
+	// used maps the given names to the artifacts they were given to
+	used map[string]string
+}
+
+func newArtifactNamer(template string, app project.App) (*artifactNamer, error) {
+	if template == "" {
+		template = defaultArtifactNameTemplate
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return nil, fmt.Errorf("template (%s) must not contain path separators", template)
+	}
+
+	hasExt := false
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		placeholder := match[2]
+		known := false
+		for _, p := range knownPlaceholders {
+			if p == placeholder {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown placeholder {%s} in template (%s), available placeholders: {%s}", placeholder, template, strings.Join(knownPlaceholders, "}, {"))
+		}
+		if placeholder == placeholderExt {
+			hasExt = true
+		}
+	}
+	if !hasExt {
+		return nil, fmt.Errorf("template (%s) must contain the {%s} placeholder", template, placeholderExt)
+	}
+
+	return &artifactNamer{template: template, app: app, used: map[string]string{}}, nil
+}
+
+// name returns the deploy dir file name of the artifact.
+// If the name is already given to an other artifact, the flavor and the ABI of the artifact are appended to it,
+// and if that is not enough, a sequence number.
+func (n *artifactNamer) name(pth string) string {
+	values := n.values(pth)
+	name := renderArtifactName(n.template, values)
+
+	unique := name
+	if previous, used := n.used[name]; used {
+		base, ext := name, ""
+		if suffix := "." + values[placeholderExt]; strings.HasSuffix(name, suffix) {
+			base, ext = strings.TrimSuffix(name, suffix), suffix
+		}
+
+		var qualifiers []string
+		for _, value := range []string{values[placeholderFlavor], values[placeholderABI]} {
+			if value != "" && !strings.Contains(base, value) {
+				qualifiers = append(qualifiers, value)
+			}
+		}
+		if len(qualifiers) > 0 {
+			unique = base + "-" + strings.Join(qualifiers, "-") + ext
+		}
+		for i := 2; n.isUsed(unique); i++ {
+			unique = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+
+		log.Warnf("%s would overwrite %s (exported from %s) in the deploy dir, exporting it as %s", pth, name, previous, unique)
+	}
+
+	n.used[unique] = pth
+	return unique
+}
+
+func (n *artifactNamer) isUsed(name string) bool {
+	_, used := n.used[name]
+	return used
+}
+
+func (n *artifactNamer) values(pth string) map[string]string {
+	fileName := filepath.Base(pth)
+	ext := filepath.Ext(fileName)
+	var flavor, abi string
+	if _, platform := artifactType(pth); platform == "android" {
+		flavor, abi = androidVariant(pth)
+	}
+	return map[string]string{
+		placeholderName:    strings.TrimSuffix(fileName, ext),
+		placeholderAppID:   n.app.ID,
+		placeholderVersion: n.app.Version,
+		placeholderFlavor:  flavor,
+		placeholderABI:     abi,
+		placeholderExt:     strings.TrimPrefix(ext, "."),
+	}
+}
+
+// renderArtifactName renders the template, the separator in front of the {ext} placeholder is always kept.
+// If nothing is rendered before the extension (for example {appId}.{ext} with an unknown app id), the original file name is used.
+func renderArtifactName(template string, values map[string]string) string {
+	var name strings.Builder
+	last := 0
+	for _, match := range placeholderPattern.FindAllStringSubmatchIndex(template, -1) {
+		name.WriteString(template[last:match[0]])
+		last = match[1]
+
+		separator, placeholder := template[match[2]:match[3]], template[match[4]:match[5]]
+		value := values[placeholder]
+		switch {
+		case placeholder == placeholderExt:
+			if strings.Trim(name.String(), "-_.") == "" {
+				name.Reset()
+				name.WriteString(values[placeholderName])
+			}
+		case value == "":
+			continue
+		case name.Len() == 0:
+			// the separator of an empty placeholder at the beginning of the name is dropped
+			separator = ""
+		}
+		name.WriteString(separator + value)
+	}
+	name.WriteString(template[last:])
+	return name.String()
+}
+
+// androidVariant returns the product flavor and the ABI of an Android artifact based on its path,
+// Gradle writes the outputs to outputs/{apk,bundle}/[<flavor>/][<abi>/]<build type>/<file>.
+func androidVariant(pth string) (string, string) {
+	parts := strings.Split(filepath.ToSlash(pth), "/")
+
+	var dirs []string
+	for i := len(parts) - 2; i > 0; i-- {
+		if (parts[i] == "apk" || parts[i] == "bundle") && parts[i-1] == "outputs" {
+			// the last dir is the build type
+			if i+1 < len(parts)-2 {
+				dirs = parts[i+1 : len(parts)-2]
+			}
+			break
+		}
+	}
+
+	var flavors []string
+	abi := ""
+	for _, dir := range dirs {
+		if isAndroidABI(dir) {
+			abi = dir
+		} else {
+			flavors = append(flavors, dir)
+		}
+	}
+
+	if abi == "" {
+		name := "-" + strings.TrimSuffix(parts[len(parts)-1], filepath.Ext(pth)) + "-"
+		for _, candidate := range androidABIs {
+			if strings.Contains(name, "-"+candidate+"-") {
+				abi = candidate
+				break
+			}
+		}
+	}
+
+	return strings.Join(flavors, "-"), abi
+}
+
+func isAndroidABI(name string) bool {
+	for _, abi := range androidABIs {
+		if name == abi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/project"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newArtifactNamer(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{name: "default", template: ""},
+		{name: "all placeholders", template: "{appId}-{version}-{flavor}-{abi}-{name}.{ext}"},
+		{name: "unknown placeholder", template: "{appID}.{ext}", wantErr: true},
+		{name: "missing extension", template: "{name}", wantErr: true},
+		{name: "path separator", template: "{flavor}/{name}.{ext}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newArtifactNamer(tt.template, project.App{})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_artifactNamer_name(t *testing.T) {
+	const outputs = "/project/platforms/android/app/build/outputs"
+
+	tests := []struct {
+		name     string
+		template string
+		// app is the app of the project, io.ionic.starter 1.0.0 if not set
+		app   *project.App
+		paths []string
+		want  []string
+	}{
+		{
+			name:  "default keeps the file names",
+			paths: []string{outputs + "/apk/release/app-release.apk", "/project/platforms/ios/build/Release-iphoneos/App.ipa"},
+			want:  []string{"app-release.apk", "App.ipa"},
+		},
+		{
+			name:     "template",
+			template: "{appId}-{version}-{flavor}-{abi}.{ext}",
+			paths: []string{
+				outputs + "/apk/free/arm64-v8a/release/app-release.apk",
+				outputs + "/apk/paid/release/app-paid-x86_64-release.apk",
+				outputs + "/bundle/release/app-release.aab",
+				"/project/ios/build/Release-iphoneos/App.ipa",
+			},
+			want: []string{
+				"io.ionic.starter-1.0.0-free-arm64-v8a.apk",
+				"io.ionic.starter-1.0.0-paid-x86_64.apk",
+				"io.ionic.starter-1.0.0.aab",
+				"io.ionic.starter-1.0.0.ipa",
+			},
+		},
+		{
+			name: "flavor collision",
+			paths: []string{
+				outputs + "/apk/free/release/app-release.apk",
+				outputs + "/apk/paid/release/app-release.apk",
+			},
+			want: []string{"app-release.apk", "app-release-paid.apk"},
+		},
+		{
+			name: "abi collision",
+			paths: []string{
+				outputs + "/apk/armeabi-v7a/release/app-release.apk",
+				outputs + "/apk/x86/release/app-release.apk",
+			},
+			want: []string{"app-release.apk", "app-release-x86.apk"},
+		},
+		{
+			name:     "collision without variant",
+			template: "{appId}.{ext}",
+			paths: []string{
+				"/project/ios/build/Release-iphoneos/App.ipa",
+				"/project/ios/build/Release-iphoneos/Extension.ipa",
+				"/project/ios/build/Release-iphoneos/Other.ipa",
+			},
+			want: []string{"io.ionic.starter.ipa", "io.ionic.starter-2.ipa", "io.ionic.starter-3.ipa"},
+		},
+		{
+			name:     "empty app id",
+			template: "{appId}.{ext}",
+			app:      &project.App{},
+			paths:    []string{outputs + "/apk/release/app-release.apk", "/project/ios/build/Release-iphoneos/App.ipa"},
+			want:     []string{"app-release.apk", "App.ipa"},
+		},
+		{
+			name:     "empty leading placeholders",
+			template: "{appId}-{flavor}-{version}.{ext}",
+			app:      &project.App{Version: "1.0.0"},
+			paths:    []string{outputs + "/apk/release/app-release.apk"},
+			want:     []string{"1.0.0.apk"},
+		},
+		{
+			name: "directory artifacts",
+			paths: []string{
+				"/project/ios/build/Debug-iphonesimulator/App.app",
+				"/project/ios/build/Debug-iphonesimulator/App.app.dSYM",
+			},
+			want: []string{"App.app", "App.app.dSYM"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := project.App{ID: "io.ionic.starter", Version: "1.0.0"}
+			if tt.app != nil {
+				app = *tt.app
+			}
+			namer, err := newArtifactNamer(tt.template, app)
+			require.NoError(t, err)
+
+			var got []string
+			for _, pth := range tt.paths {
+				got = append(got, namer.name(pth))
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_androidVariant(t *testing.T) {
+	tests := []struct {
+		pth        string
+		wantFlavor string
+		wantABI    string
+	}{
+		{pth: "app/build/outputs/apk/release/app-release.apk"},
+		{pth: "app/build/outputs/apk/release/app-armeabi-v7a-release.apk", wantABI: "armeabi-v7a"},
+		{pth: "app/build/outputs/apk/free/release/app-free-release.apk", wantFlavor: "free"},
+		{pth: "app/build/outputs/apk/freeProd/x86_64/release/app-release.apk", wantFlavor: "freeProd", wantABI: "x86_64"},
+		{pth: "app/build/outputs/bundle/paidRelease/app-paid-release.aab"},
+		{pth: "app/build/outputs/apk/app.apk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pth, func(t *testing.T) {
+			flavor, abi := androidVariant(tt.pth)
+			require.Equal(t, tt.wantFlavor, flavor)
+			require.Equal(t, tt.wantABI, abi)
+		})
+	}
+}
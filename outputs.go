@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/discovery"
+	ver "github.com/hashicorp/go-version"
+)
+
+// cordovaPlatformVersion returns the version of the cordova platform package (cordova-android, cordova-ios) of the project,
+// based on the installed package, platforms/platforms.json or the dependency in package.json.
+// It returns nil if the version is unknown, for example if the platform is added from a git url.
+func cordovaPlatformVersion(workDir, pkg string) *ver.Version {
+	var installed struct {
+		Version string `json:"version"`
+	}
+	if readJSONFile(filepath.Join(workDir, "node_modules", pkg, "package.json"), &installed) {
+		if v, err := ver.NewVersion(installed.Version); err == nil {
+			return v
+		}
+	}
+
+	var platforms map[string]string
+	if readJSONFile(filepath.Join(workDir, "platforms", "platforms.json"), &platforms) {
+		if v, err := ver.NewVersion(platforms[strings.TrimPrefix(pkg, "cordova-")]); err == nil {
+			return v
+		}
+	}
+
+	var packageJSON struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if readJSONFile(filepath.Join(workDir, "package.json"), &packageJSON) {
+		spec, ok := packageJSON.DevDependencies[pkg]
+		if !ok {
+			spec = packageJSON.Dependencies[pkg]
+		}
+		// the lower bound of a range, like ^12.0.0 or ~11.0.1
+		if v, err := ver.NewVersion(strings.TrimLeft(spec, "^~>= ")); err == nil {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func readJSONFile(pth string, v interface{}) bool {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(content, v) == nil
+}
+
+// cordovaOutputRules returns where cordova writes the outputs of the platforms,
+// the output directories of both layouts are searched if the platform version is unknown.
+func cordovaOutputRules(workDir string, platforms []string, target, configuration string, isAAB bool) []discovery.Rule {
+	var rules []discovery.Rule
+	for _, platform := range platforms {
+		switch platform {
+		case "ios":
+			for _, dir := range cordovaIOSOutputDirs(workDir, target, configuration, cordovaPlatformVersion(workDir, "cordova-ios")) {
+				rules = append(rules, iosOutputRules(dir)...)
+			}
+		case "android":
+			ext := "apk"
+			if isAAB {
+				ext = "aab"
+			}
+			for _, dir := range cordovaAndroidOutputDirs(workDir, isAAB, cordovaPlatformVersion(workDir, "cordova-android")) {
+				rules = append(rules, discovery.Rule{Dir: dir, Ext: ext})
+			}
+		}
+	}
+	return rules
+}
+
+func iosOutputRules(dir string) []discovery.Rule {
+	return []discovery.Rule{{Dir: dir, Ext: "ipa"}, {Dir: dir, Ext: "dSYM"}, {Dir: dir, Ext: "app"}}
+}
+
+func cordovaIOSOutputDirs(workDir, target, configuration string, cordovaIOSVersion *ver.Version) []string {
+	dirs := getIosOutputCandidateDirsPaths(workDir, target, configuration)
+	switch {
+	case cordovaIOSVersion == nil:
+		return dirs
+	case cordovaIOSVersion.Segments()[0] < 7:
+		return dirs[:1]
+	default:
+		return dirs[1:]
+	}
+}
+
+func cordovaAndroidOutputDirs(workDir string, isAAB bool, cordovaAndroidVersion *ver.Version) []string {
+	outputType := "apk"
+	if isAAB {
+		outputType = "bundle"
+	}
+
+	// cordova-android 7 moved the app into the app module
+	legacyDir := filepath.Join(workDir, "platforms", "android", "build", "outputs", outputType)
+	dir := filepath.Join(workDir, "platforms", "android", "app", "build", "outputs", outputType)
+	switch {
+	case cordovaAndroidVersion == nil:
+		return []string{dir, legacyDir}
+	case cordovaAndroidVersion.Segments()[0] < 7:
+		return []string{legacyDir}
+	default:
+		return []string{dir}
+	}
+}
+
+// findOutputs returns the outputs with the extension, which are new, changed or rewritten since the build start.
+// The outputs, which were not rewritten by the build (like the outputs of an earlier build restored from a cache), are ignored,
+// unless the build did not write any output with the extension, as an up-to-date build may keep its previous outputs.
+func findOutputs(outputs discovery.Outputs, ext string) []string {
+	changed := outputs.Find(ext)
+	stale := outputs.Stale(ext)
+	if len(changed) == 0 && len(stale) > 0 {
+		log.Warnf("The build did not write any %s output, using the existing ones, which may come from an earlier build: %s", ext, strings.Join(stale, ", "))
+		return stale
+	}
+	if len(stale) > 0 {
+		log.Warnf("Ignoring the %s outputs, which were not rewritten by the build: %s", ext, strings.Join(stale, ", "))
+	}
+	return changed
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/discovery"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cordovaPlatformVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "installed package",
+			files: map[string]string{
+				"node_modules/cordova-android/package.json": `{"version": "12.0.1"}`,
+				"package.json": `{"devDependencies": {"cordova-android": "^11.0.0"}}`,
+			},
+			want: "12.0.1",
+		},
+		{
+			name:  "platforms.json",
+			files: map[string]string{"platforms/platforms.json": `{"android": "6.4.0"}`},
+			want:  "6.4.0",
+		},
+		{
+			name:  "package.json dependency",
+			files: map[string]string{"package.json": `{"dependencies": {"cordova-android": "~10.1.2"}}`},
+			want:  "10.1.2",
+		},
+		{
+			name:  "git dependency",
+			files: map[string]string{"package.json": `{"devDependencies": {"cordova-android": "git+https://github.com/apache/cordova-android.git"}}`},
+		},
+		{
+			name:  "no dependency",
+			files: map[string]string{"package.json": `{}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			writeFiles(t, workDir, tt.files)
+
+			got := cordovaPlatformVersion(workDir, "cordova-android")
+			if tt.want == "" {
+				require.Nil(t, got)
+				return
+			}
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func Test_cordovaOutputRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		platforms []string
+		isAAB     bool
+		want      []discovery.Rule
+	}{
+		{
+			name: "current platforms",
+			files: map[string]string{
+				"package.json": `{"devDependencies": {"cordova-android": "^12.0.0", "cordova-ios": "^7.0.0"}}`,
+			},
+			platforms: []string{"android", "ios"},
+			isAAB:     true,
+			want: []discovery.Rule{
+				{Dir: "platforms/android/app/build/outputs/bundle", Ext: "aab"},
+				{Dir: "platforms/ios/build/Release-iphoneos", Ext: "ipa"},
+				{Dir: "platforms/ios/build/Release-iphoneos", Ext: "dSYM"},
+				{Dir: "platforms/ios/build/Release-iphoneos", Ext: "app"},
+			},
+		},
+		{
+			name: "legacy platforms",
+			files: map[string]string{
+				"package.json": `{"devDependencies": {"cordova-android": "6.4.0", "cordova-ios": "6.3.0"}}`,
+			},
+			platforms: []string{"android", "ios"},
+			want: []discovery.Rule{
+				{Dir: "platforms/android/build/outputs/apk", Ext: "apk"},
+				{Dir: "platforms/ios/build/device", Ext: "ipa"},
+				{Dir: "platforms/ios/build/device", Ext: "dSYM"},
+				{Dir: "platforms/ios/build/device", Ext: "app"},
+			},
+		},
+		{
+			name:      "unknown platform version",
+			files:     map[string]string{"package.json": `{}`},
+			platforms: []string{"android"},
+			want: []discovery.Rule{
+				{Dir: "platforms/android/app/build/outputs/apk", Ext: "apk"},
+				{Dir: "platforms/android/build/outputs/apk", Ext: "apk"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			writeFiles(t, workDir, tt.files)
+
+			var want []discovery.Rule
+			for _, rule := range tt.want {
+				want = append(want, discovery.Rule{Dir: filepath.Join(workDir, rule.Dir), Ext: rule.Ext})
+			}
+			require.Equal(t, want, cordovaOutputRules(workDir, tt.platforms, "device", "release", tt.isAAB))
+		})
+	}
+}
+
+func Test_findOutputs(t *testing.T) {
+	outputs := discovery.Outputs{
+		{Path: "/android/release/app.apk", Ext: "apk", Changed: false},
+		{Path: "/ios/New.ipa", Ext: "ipa", Changed: true},
+		{Path: "/ios/Unchanged.ipa", Ext: "ipa", Changed: false},
+	}
+
+	require.Equal(t, []string{"/ios/New.ipa"}, findOutputs(outputs, "ipa"))
+	// the build did not write any apk, like an up-to-date build
+	require.Equal(t, []string{"/android/release/app.apk"}, findOutputs(outputs, "apk"))
+	require.Empty(t, findOutputs(outputs, "aab"))
+}
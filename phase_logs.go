@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/phaselog"
+)
+
+// phaseLogPathEnvKeyFormat is the env of a phase's log file path, like BITRISE_IONIC_BUILD_LOG_PATH (or BITRISE_IONIC_IOS_BUILD_LOG_PATH of the ios-build log)
+const phaseLogPathEnvKeyFormat = "BITRISE_IONIC_%s_LOG_PATH"
+
+// The build phases, which commands are logged into a separate file
+const (
+	phaseInstall = "install"
+	phaseLogin   = "login"
+	phasePrepare = "prepare"
+	phaseBuild   = "build"
+)
+
+// startPhase starts logging the commands of the phase, a log file, which can't be created, does not fail the build
+func startPhase(phases *phaselog.Runner, phase string) {
+	if err := phases.Start(phase); err != nil {
+		log.Warnf("Failed to create the %s log file, error: %s", phase, err)
+	}
+}
+
+// exportPhaseLogs closes the log file of the current phase and exports the path of every phase's log file
+func exportPhaseLogs(phases *phaselog.Runner) {
+	if err := phases.Stop(); err != nil {
+		log.Warnf("Failed to close the log file, error: %s", err)
+	}
+
+	logs := phases.Logs()
+	if len(logs) == 0 {
+		return
+	}
+	fmt.Println()
+	for _, l := range logs {
+		envKey := fmt.Sprintf(phaseLogPathEnvKeyFormat, strings.ToUpper(strings.ReplaceAll(l.Phase, "-", "_")))
+		if err := exportEnvironment(phases, envKey, l.Path); err != nil {
+			log.Warnf("Failed to export the %s log path, error: %s", l.Phase, err)
+			continue
+		}
+		log.Donef("The %s log path is now available in the Environment Variable: %s (value: %s)", l.Phase, envKey, l.Path)
+	}
+}
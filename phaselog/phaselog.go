@@ -0,0 +1,191 @@
+// Package phaselog saves the output of the commands into a log file per build phase (install, login, prepare, build),
+// so that the full output is available, even if the build log is truncated.
+package phaselog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/prefix"
+	"github.com/bitrise-steplib/steps-ionic-archive/redact"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+// fileNameFormat is the name of a phase's log file, followed by the start time of the step
+const fileNameFormat = "ionic-%s-%s.log"
+
+// Log is the log file of a phase
+type Log struct {
+	Phase string
+	Path  string
+}
+
+// Runner is a runner.Runner, which writes the output of the commands into the log file of the current phase,
+// in addition to the commands' stdout and stderr.
+// The commands, executed before the first or after the last phase, are not logged.
+// The output of a concurrent build (a prefix.Output stdout) is logged line by line with the build's prefix.
+// The output is expected to be redacted by the wrapped runner, the command lines are redacted by the Runner.
+type Runner struct {
+	runner.Runner
+
+	dir       string
+	timestamp string
+	secrets   redact.Secrets
+
+	mu      sync.Mutex
+	current *lockedWriter
+	file    *os.File
+	logs    []Log
+}
+
+// NewRunner returns a Runner, which wraps the runner and writes the log files into the dir,
+// their names include the start time.
+func NewRunner(r runner.Runner, dir string, startTime time.Time, secrets redact.Secrets) *Runner {
+	return &Runner{Runner: r, dir: dir, timestamp: startTime.UTC().Format("20060102-150405"), secrets: secrets}
+}
+
+// Start closes the log file of the previous phase and opens the log file of the phase,
+// the log of a phase started again is appended.
+func (r *Runner) Start(phase string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.close(); err != nil {
+		return err
+	}
+
+	pth := filepath.Join(r.dir, fmt.Sprintf(fileNameFormat, phase, r.timestamp))
+	file, err := os.OpenFile(pth, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.current = &lockedWriter{w: file}
+
+	for _, l := range r.logs {
+		if l.Phase == phase {
+			return nil
+		}
+	}
+	r.logs = append(r.logs, Log{Phase: phase, Path: pth})
+	return nil
+}
+
+// Create creates an additional log file, which is written by the caller (like the log of a platform built concurrently with the others),
+// its name includes the start time like the name of the phases' log files, and it is returned by Logs too.
+func (r *Runner) Create(name string) (*os.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pth := filepath.Join(r.dir, fmt.Sprintf(fileNameFormat, name, r.timestamp))
+	file, err := os.Create(pth)
+	if err != nil {
+		return nil, err
+	}
+	r.logs = append(r.logs, Log{Phase: name, Path: pth})
+	return file, nil
+}
+
+// Stop closes the log file of the current phase
+func (r *Runner) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.close()
+}
+
+// Logs returns the log files in the order of the phases (and of the created log files)
+func (r *Runner) Logs() []Log {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Log{}, r.logs...)
+}
+
+// Run runs the command with its stdout and stderr written into the log file of the current phase too
+func (r *Runner) Run(cmd *runner.Command) error {
+	w := r.writer()
+	if w == nil {
+		return r.Runner.Run(cmd)
+	}
+	if output, ok := cmd.Stdout.(prefix.Output); ok {
+		// the lines of the concurrent builds are not mixed in the log
+		prefixed := prefix.NewWriter(w, output.Prefix())
+		defer func() {
+			_ = prefixed.Flush()
+		}()
+		w = prefixed
+	}
+	r.writeCommand(w, cmd)
+
+	logged := *cmd
+	logged.Stdout = teeWriter(cmd.Stdout, w)
+	if cmd.Stderr == cmd.Stdout {
+		// the command's stdout and stderr are written by a single pipe, if they are the same
+		logged.Stderr = logged.Stdout
+	} else {
+		logged.Stderr = teeWriter(cmd.Stderr, w)
+	}
+	return r.Runner.Run(&logged)
+}
+
+// RunAndReturnTrimmedCombinedOutput runs the command and writes its output into the log file of the current phase
+func (r *Runner) RunAndReturnTrimmedCombinedOutput(cmd *runner.Command) (string, error) {
+	out, err := r.Runner.RunAndReturnTrimmedCombinedOutput(cmd)
+	if w := r.writer(); w != nil {
+		r.writeCommand(w, cmd)
+		if out != "" {
+			_, _ = io.WriteString(w, out+"\n")
+		}
+	}
+	return out, err
+}
+
+func (r *Runner) writer() io.Writer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current == nil {
+		return nil
+	}
+	return r.current
+}
+
+func (r *Runner) close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	r.current = nil
+	return err
+}
+
+// writeCommand writes the redacted command line into the log, before its output
+func (r *Runner) writeCommand(w io.Writer, cmd *runner.Command) {
+	_, _ = fmt.Fprintf(w, "$ %s\n", r.secrets.Redact(cmd.PrintableCommandArgs()))
+}
+
+func teeWriter(w io.Writer, log io.Writer) io.Writer {
+	if w == nil {
+		return log
+	}
+	return io.MultiWriter(w, log)
+}
+
+// lockedWriter serializes the writes of the commands executed concurrently
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
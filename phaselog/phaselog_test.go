@@ -0,0 +1,87 @@
+package phaselog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/prefix"
+	"github.com/bitrise-steplib/steps-ionic-archive/redact"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Runner(t *testing.T) {
+	fake := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		return "output of " + cmd.Args[0], nil
+	})
+	dir := t.TempDir()
+	r := NewRunner(fake, dir, time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC), redact.NewSecrets([]string{"s3cr3t"}, nil))
+
+	var out bytes.Buffer
+	run := func(args ...string) {
+		require.NoError(t, r.Run(runner.New("ionic", args...).SetStdout(&out).SetStderr(&out)))
+	}
+
+	// not logged before the first phase
+	run("-v")
+
+	require.NoError(t, r.Start("login"))
+	run("login", "user", "s3cr3t")
+	require.NoError(t, r.Start("build"))
+	run("build")
+	_, err := r.RunAndReturnTrimmedCombinedOutput(runner.New("cordova", "-v"))
+	require.NoError(t, err)
+	require.NoError(t, r.Start("login"))
+	run("logout")
+	require.NoError(t, r.Stop())
+
+	// not logged after the last phase
+	run("info")
+
+	// the output is still written to the command's stdout
+	require.Equal(t, "output of -v\noutput of login\noutput of build\noutput of logout\noutput of info\n", out.String())
+
+	loginPth := filepath.Join(dir, "ionic-login-20261017-123000.log")
+	buildPth := filepath.Join(dir, "ionic-build-20261017-123000.log")
+	require.Equal(t, []Log{{Phase: "login", Path: loginPth}, {Phase: "build", Path: buildPth}}, r.Logs())
+
+	// the log files written by the caller are listed after the phases
+	file, err := r.Create("ios-build")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	iosPth := filepath.Join(dir, "ionic-ios-build-20261017-123000.log")
+	require.Equal(t, iosPth, file.Name())
+	require.Equal(t, []Log{{Phase: "login", Path: loginPth}, {Phase: "build", Path: buildPth}, {Phase: "ios-build", Path: iosPth}}, r.Logs())
+
+	content, err := os.ReadFile(loginPth)
+	require.NoError(t, err)
+	require.Equal(t, "$ ionic \"login\" \"user\" \"***\"\noutput of login\n$ ionic \"logout\"\noutput of logout\n", string(content))
+
+	content, err = os.ReadFile(buildPth)
+	require.NoError(t, err)
+	require.Equal(t, "$ ionic \"build\"\noutput of build\n$ cordova \"-v\"\noutput of -v\n", string(content))
+}
+
+func Test_Runner_concurrentBuild(t *testing.T) {
+	fake := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		return "output of " + cmd.Args[len(cmd.Args)-1], nil
+	})
+	dir := t.TempDir()
+	r := NewRunner(fake, dir, time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC), redact.NewSecrets(nil, nil))
+
+	var out bytes.Buffer
+	require.NoError(t, r.Start("build"))
+	output := prefix.NewOutput(&out, "[ios] ")
+	require.NoError(t, r.Run(runner.New("ionic", "build", "ios").SetStdout(output).SetStderr(output)))
+	require.NoError(t, r.Stop())
+
+	// the output of the build is not prefixed by the Runner
+	require.Equal(t, "output of ios\n", out.String())
+
+	content, err := os.ReadFile(filepath.Join(dir, "ionic-build-20261017-123000.log"))
+	require.NoError(t, err)
+	require.Equal(t, "[ios] $ ionic \"build\" \"ios\"\n[ios] output of ios\n", string(content))
+}
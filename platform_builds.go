@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/prefix"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const (
+	// platformLogNameFormat is the name of the build log of a platform, built concurrently with the other platforms,
+	// it is exported like the phase logs (as BITRISE_IONIC_IOS_BUILD_LOG_PATH)
+	platformLogNameFormat = "%s-build"
+	// platformStatusEnvKeyFormat is the env of a platform's build status, like BITRISE_IONIC_IOS_STATUS
+	platformStatusEnvKeyFormat = "BITRISE_IONIC_%s_STATUS"
+)
+
+// The build statuses of a platform
+const (
+	platformStatusSucceeded = "succeeded"
+	platformStatusFailed    = "failed"
+	// platformStatusSkipped is the status of the platforms, which were not built, as an other platform failed before
+	platformStatusSkipped = "skipped"
+)
+
+// platformBuildFailure is the error of the platform builds, if any of them failed
+type platformBuildFailure struct {
+	platforms []string
+	statuses  map[string]string
+	errs      map[string]error
+}
+
+// Error returns the errors of the failed builds joined
+func (f *platformBuildFailure) Error() string {
+	var failures []string
+	for _, platform := range f.platforms {
+		if err, ok := f.errs[platform]; ok {
+			failures = append(failures, fmt.Sprintf("%s: %s", platform, err))
+		}
+	}
+	return fmt.Sprintf("%d of %d platform builds failed, %s", len(failures), len(f.platforms), strings.Join(failures, "; "))
+}
+
+// succeeded returns the platforms, which were built successfully
+func (f *platformBuildFailure) succeeded() []string {
+	var succeeded []string
+	for _, platform := range f.platforms {
+		if f.statuses[platform] == platformStatusSucceeded {
+			succeeded = append(succeeded, platform)
+		}
+	}
+	return succeeded
+}
+
+// runPlatformBuilds builds the platforms one after the other, or concurrently if parallel is set.
+// The output of the concurrent builds is written to out with every line prefixed by the platform (like [ios]),
+// and it is saved into a log file per platform, created by createLog.
+// The platforms built one after the other stop at the first failure, unless continueOnFailure is set.
+// Every concurrent build is waited for, even if an other one fails.
+// Every platform build is timed by the timer (as "build <platform>"), the phase entered before is finished.
+// If any of the builds fails, the returned error is a *platformBuildFailure.
+func runPlatformBuilds(r runner.Runner, platforms []string, parallel, continueOnFailure bool, out io.Writer, createLog func(name string) (*os.File, error), timer *phaseTimer, build func(r runner.Runner, platform string) error) error {
+	timer.leave()
+	timedBuild := func(r runner.Runner, platform string) error {
+		defer timer.start(fmt.Sprintf(timingPlatformBuildFormat, platform))()
+		return build(r, platform)
+	}
+
+	failure := &platformBuildFailure{platforms: platforms, statuses: map[string]string{}, errs: map[string]error{}}
+	for _, platform := range platforms {
+		failure.statuses[platform] = platformStatusSkipped
+	}
+	setResult := func(platform string, err error) {
+		if err != nil {
+			failure.statuses[platform] = platformStatusFailed
+			failure.errs[platform] = err
+		} else {
+			failure.statuses[platform] = platformStatusSucceeded
+		}
+	}
+
+	if !parallel || len(platforms) < 2 {
+		for _, platform := range platforms {
+			err := timedBuild(r, platform)
+			setResult(platform, err)
+			if err != nil && !continueOnFailure {
+				break
+			}
+		}
+	} else {
+		errs, err := runConcurrentBuilds(r, platforms, out, createLog, timedBuild)
+		if err != nil {
+			return err
+		}
+		for i, platform := range platforms {
+			setResult(platform, errs[i])
+		}
+	}
+
+	if len(failure.errs) > 0 {
+		return failure
+	}
+	return nil
+}
+
+// runConcurrentBuilds builds the platforms at the same time and returns the errors of the builds in the order of the platforms
+func runConcurrentBuilds(r runner.Runner, platforms []string, out io.Writer, createLog func(name string) (*os.File, error), build func(r runner.Runner, platform string) error) ([]error, error) {
+	log.Printf("Building %s concurrently", strings.Join(platforms, ", "))
+
+	logFiles := make([]*os.File, len(platforms))
+	for i, platform := range platforms {
+		logFile, err := createLog(fmt.Sprintf(platformLogNameFormat, platform))
+		if err != nil {
+			for _, created := range logFiles[:i] {
+				if err := created.Close(); err != nil {
+					log.Warnf("Failed to close the build log, error: %s", err)
+				}
+			}
+			return nil, fmt.Errorf("failed to create the %s build log, error: %s", platform, err)
+		}
+		logFiles[i] = logFile
+	}
+
+	errs := make([]error, len(platforms))
+	var wg sync.WaitGroup
+	for i, platform := range platforms {
+		wg.Add(1)
+		go func(i int, platform string) {
+			defer wg.Done()
+
+			prefixed := prefix.NewWriter(out, "["+platform+"] ")
+			// the phase log prefixes the lines too
+			output := prefix.NewOutput(io.MultiWriter(prefixed, logFiles[i]), "["+platform+"] ")
+			errs[i] = build(runner.NewOutput(r, output), platform)
+
+			if err := prefixed.Flush(); err != nil {
+				log.Warnf("Failed to write the %s build output, error: %s", platform, err)
+			}
+			if err := logFiles[i].Close(); err != nil {
+				log.Warnf("Failed to close the %s build log, error: %s", platform, err)
+			}
+			log.Printf("The %s build log is saved to: %s", platform, logFiles[i].Name())
+		}(i, platform)
+	}
+	wg.Wait()
+
+	return errs, nil
+}
+
+// exportPlatformStatuses exports the build status of every platform
+func exportPlatformStatuses(r runner.Runner, platforms []string, statuses map[string]string) error {
+	for _, platform := range platforms {
+		envKey := fmt.Sprintf(platformStatusEnvKeyFormat, strings.ToUpper(platform))
+		if err := exportEnvironment(r, envKey, statuses[platform]); err != nil {
+			return err
+		}
+		log.Donef("The %s build status is now available in the Environment Variable: %s (value: %s)", platform, envKey, statuses[platform])
+	}
+	return nil
+}
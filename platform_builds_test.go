@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer, which can be written concurrently
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func Test_runPlatformBuilds(t *testing.T) {
+	fake := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		if cmd.Args[0] == "ios" {
+			return "error: signing failed", errors.New("exit status 65")
+		}
+		return "BUILD SUCCESSFUL", nil
+	})
+	build := func(r runner.Runner, platform string) error {
+		return r.Run(runner.New("build", platform).SetStdout(os.Stdout).SetStderr(os.Stderr))
+	}
+
+	createLog := func(logDir string) func(name string) (*os.File, error) {
+		return func(name string) (*os.File, error) {
+			return os.Create(filepath.Join(logDir, name+".log"))
+		}
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		fake := runner.NewFake(fake.Handler)
+		logDir := t.TempDir()
+		var out syncBuffer
+		err := runPlatformBuilds(fake, []string{"ios", "android"}, false, false, &out, createLog(logDir), nil, build)
+		require.EqualError(t, err, "1 of 2 platform builds failed, ios: exit status 65")
+		// the first failure stops the build
+		require.Equal(t, []string{"build ios"}, fake.CommandStrings())
+		require.Equal(t, map[string]string{"ios": "failed", "android": "skipped"}, err.(*platformBuildFailure).statuses)
+		require.NoFileExists(t, filepath.Join(logDir, "ios-build.log"))
+	})
+
+	t.Run("sequential, continue on failure", func(t *testing.T) {
+		fake := runner.NewFake(fake.Handler)
+		var out syncBuffer
+		err := runPlatformBuilds(fake, []string{"ios", "android"}, false, true, &out, createLog(t.TempDir()), nil, build)
+		require.EqualError(t, err, "1 of 2 platform builds failed, ios: exit status 65")
+		require.Equal(t, []string{"build ios", "build android"}, fake.CommandStrings())
+		require.Equal(t, []string{"android"}, err.(*platformBuildFailure).succeeded())
+	})
+
+	t.Run("succeeded", func(t *testing.T) {
+		var out syncBuffer
+		require.NoError(t, runPlatformBuilds(runner.NewFake(nil), []string{"ios", "android"}, true, false, &out, createLog(t.TempDir()), nil, build))
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		fake := runner.NewFake(fake.Handler)
+		logDir := t.TempDir()
+		var out syncBuffer
+		err := runPlatformBuilds(fake, []string{"android", "ios"}, true, false, &out, createLog(logDir), nil, build)
+		require.EqualError(t, err, "1 of 2 platform builds failed, ios: exit status 65")
+		// the android build is not stopped by the ios failure
+		require.ElementsMatch(t, []string{"build android", "build ios"}, fake.CommandStrings())
+
+		require.Contains(t, out.buf.String(), "[android] BUILD SUCCESSFUL\n")
+		require.Contains(t, out.buf.String(), "[ios] error: signing failed\n")
+
+		content, err := os.ReadFile(filepath.Join(logDir, "ios-build.log"))
+		require.NoError(t, err)
+		require.Equal(t, "error: signing failed\n", string(content))
+		content, err = os.ReadFile(filepath.Join(logDir, "android-build.log"))
+		require.NoError(t, err)
+		require.Equal(t, "BUILD SUCCESSFUL\n", string(content))
+	})
+}
@@ -0,0 +1,86 @@
+// Package prefix marks every line of an output with a prefix, to tell apart the outputs of the concurrent builds
+package prefix
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Writer writes the content with every line prefixed, the content is written line by line (\n and \r end a line),
+// so that the lines of the concurrent writers of the same output are not mixed.
+// Flush has to be called to write the last, unterminated line.
+type Writer struct {
+	w      io.Writer
+	prefix string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewWriter returns a Writer, which writes the prefixed lines to w
+func NewWriter(w io.Writer, prefix string) *Writer {
+	return &Writer{w: w, prefix: prefix}
+}
+
+// Write writes the prefixed complete lines of the content and buffers the rest
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	var lines []byte
+	for {
+		end := bytes.IndexAny(w.buf, "\r\n")
+		if end == -1 {
+			break
+		}
+		lines = append(lines, w.prefix...)
+		lines = append(lines, w.buf[:end+1]...)
+		w.buf = w.buf[end+1:]
+	}
+	w.buf = append([]byte{}, w.buf...)
+
+	if len(lines) == 0 {
+		return len(p), nil
+	}
+	if _, err := w.w.Write(lines); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the prefixed buffered content, terminated by a new line
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := w.prefix + string(w.buf) + "\n"
+	w.buf = nil
+	_, err := io.WriteString(w.w, line)
+	return err
+}
+
+// Output is the output of a concurrent build, which tells the prefix of its lines,
+// so that the wrapping runners, which log the output too (like the phase logs), can prefix the logged lines the same way.
+type Output interface {
+	io.Writer
+	Prefix() string
+}
+
+type output struct {
+	io.Writer
+	prefix string
+}
+
+func (o output) Prefix() string {
+	return o.prefix
+}
+
+// NewOutput returns an Output, which writes the content to w as it is
+func NewOutput(w io.Writer, prefix string) Output {
+	return output{Writer: w, prefix: prefix}
+}
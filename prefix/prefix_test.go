@@ -0,0 +1,33 @@
+package prefix
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Writer(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, "[ios] ")
+
+	for _, chunk := range []string{"Compiling ", "App.swift\nLinking\n", "progress 50%\r", "progress 100%\rBUILD SUCCEEDED"} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+	require.Equal(t, "[ios] Compiling App.swift\n[ios] Linking\n[ios] progress 50%\r[ios] progress 100%\r", out.String())
+
+	require.NoError(t, w.Flush())
+	require.Equal(t, "[ios] Compiling App.swift\n[ios] Linking\n[ios] progress 50%\r[ios] progress 100%\r[ios] BUILD SUCCEEDED\n", out.String())
+}
+
+func Test_NewOutput(t *testing.T) {
+	var out bytes.Buffer
+	o := NewOutput(&out, "[ios] ")
+
+	_, err := o.Write([]byte("BUILD SUCCEEDED\n"))
+	require.NoError(t, err)
+	require.Equal(t, "BUILD SUCCEEDED\n", out.String())
+	require.Equal(t, "[ios] ", o.Prefix())
+}
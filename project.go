@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/colorstring"
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-ionic-archive/capacitor"
+	"github.com/bitrise-steplib/steps-ionic-archive/project"
+)
+
+const integrationAuto = "auto"
+
+// resolveIntegration detects the project type and returns the integration to build the project with.
+// An explicitly selected integration is respected, but a mismatch with the detected one is reported.
+func resolveIntegration(workDir, selected string, platforms []string) (string, error) {
+	fmt.Println()
+	log.Infof("Detecting project type")
+
+	info, err := project.Detect(workDir)
+	if err != nil {
+		if selected != integrationAuto {
+			log.Warnf("Failed to detect project type: %s", err)
+			return selected, checkNativeProjects(workDir, selected, platforms)
+		}
+		return "", fmt.Errorf("failed to detect project type: %s", err)
+	}
+
+	log.Printf("framework: %s", colorstring.Green(string(info.Framework)))
+	log.Printf("integration: %s", colorstring.Green(string(info.Integration)))
+	log.Printf("detected based on: %s", strings.Join(info.Evidence, ", "))
+
+	integration := string(info.Integration)
+	switch {
+	case selected != integrationAuto && selected != integration:
+		log.Warnf("The selected integration (%s) differs from the detected one (%s), building with %s", selected, integration, selected)
+		integration = selected
+	case info.Integration == project.WebOnly:
+		return "", fmt.Errorf("the project has no native integration (neither config.xml nor capacitor.config.{json,ts} found in %s), "+
+			"add one with `ionic integrations enable capacitor` or `ionic integrations enable cordova` and commit the generated files", workDir)
+	}
+
+	return integration, checkNativeProjects(workDir, integration, platforms)
+}
+
+// checkNativeProjects fails early if a Capacitor native project is missing,
+// as `npx cap sync` does not create them, while cordova adds the missing platforms during the build.
+func checkNativeProjects(workDir, integration string, platforms []string) error {
+	if integration != integrationCapacitor {
+		return nil
+	}
+
+	for _, platform := range platforms {
+		dir := capacitor.AndroidProjectDir(workDir)
+		if platform == "ios" {
+			dir = capacitor.IOSProjectDir(workDir)
+		}
+
+		exist, err := pathutil.IsDirExists(dir)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return fmt.Errorf("the %s native project (%s) does not exist, add it with `npx cap add %s` and commit it", platform, dir, platform)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package project
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/capacitor"
+)
+
+// App identifies the app built from the project
+type App struct {
+	ID      string
+	Version string
+}
+
+type widget struct {
+	ID      string `xml:"id,attr"`
+	Version string `xml:"version,attr"`
+}
+
+// ReadApp reads the app id and version of the project:
+// from the widget element of config.xml for Cordova projects,
+// from the Capacitor config and the version of package.json for Capacitor projects.
+func ReadApp(projectDir string, integration Integration) (App, error) {
+	switch integration {
+	case Cordova:
+		pth := filepath.Join(projectDir, "config.xml")
+		content, err := os.ReadFile(pth)
+		if err != nil {
+			return App{}, err
+		}
+
+		var w widget
+		if err := xml.Unmarshal(content, &w); err != nil {
+			return App{}, fmt.Errorf("failed to parse %s: %s", pth, err)
+		}
+		return App{ID: w.ID, Version: w.Version}, nil
+	case Capacitor:
+		config, err := capacitor.ReadConfig(projectDir)
+		if err != nil {
+			return App{}, err
+		}
+
+		var pkg struct {
+			Version string `json:"version"`
+		}
+		if _, err := readJSON(filepath.Join(projectDir, "package.json"), &pkg); err != nil {
+			return App{}, err
+		}
+		return App{ID: config.AppID, Version: pkg.Version}, nil
+	}
+	return App{}, fmt.Errorf("unsupported integration: %s", integration)
+}
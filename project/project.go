@@ -0,0 +1,176 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/capacitor"
+)
+
+// Integration is the native runtime of an Ionic project
+type Integration string
+
+// Integrations
+const (
+	Cordova   Integration = "cordova"
+	Capacitor Integration = "capacitor"
+	WebOnly   Integration = "web-only"
+)
+
+// Framework is the web framework of an Ionic project
+type Framework string
+
+// Frameworks
+const (
+	Angular Framework = "angular"
+	React   Framework = "react"
+	Vue     Framework = "vue"
+	Unknown Framework = "unknown"
+)
+
+// Info describes the detected project setup
+type Info struct {
+	Integration Integration
+	Framework   Framework
+	// Evidence lists the findings the detection is based on, to be printed for diagnosis
+	Evidence []string
+}
+
+type ionicConfig struct {
+	Type         string                     `json:"type"`
+	Integrations map[string]json.RawMessage `json:"integrations"`
+}
+
+type packageJSON struct {
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+func (p packageJSON) has(name string) bool {
+	if _, ok := p.Dependencies[name]; ok {
+		return true
+	}
+	_, ok := p.DevDependencies[name]
+	return ok
+}
+
+// Detect inspects ionic.config.json, config.xml, capacitor.config.{json,ts} and package.json in the project dir
+// and decides which native integration the project uses.
+func Detect(projectDir string) (Info, error) {
+	var info Info
+
+	var ionicConf ionicConfig
+	hasIonicConfig, err := readJSON(filepath.Join(projectDir, "ionic.config.json"), &ionicConf)
+	if err != nil {
+		return info, err
+	}
+	if hasIonicConfig {
+		info.Evidence = append(info.Evidence, fmt.Sprintf("ionic.config.json (type: %s)", ionicConf.Type))
+	}
+
+	var pkg packageJSON
+	hasPackageJSON, err := readJSON(filepath.Join(projectDir, "package.json"), &pkg)
+	if err != nil {
+		return info, err
+	}
+
+	hasConfigXML, err := isFileExists(filepath.Join(projectDir, "config.xml"))
+	if err != nil {
+		return info, err
+	}
+	if hasConfigXML {
+		info.Evidence = append(info.Evidence, "config.xml")
+	}
+
+	hasCapacitorConfig := false
+	for _, name := range capacitor.ConfigFileNames {
+		exists, err := isFileExists(filepath.Join(projectDir, name))
+		if err != nil {
+			return info, err
+		}
+		if exists {
+			hasCapacitorConfig = true
+			info.Evidence = append(info.Evidence, name)
+			break
+		}
+	}
+
+	_, cordovaIntegration := ionicConf.Integrations["cordova"]
+	_, capacitorIntegration := ionicConf.Integrations["capacitor"]
+	if cordovaIntegration {
+		info.Evidence = append(info.Evidence, "cordova integration enabled in ionic.config.json")
+	}
+	if capacitorIntegration {
+		info.Evidence = append(info.Evidence, "capacitor integration enabled in ionic.config.json")
+	}
+	if pkg.has("@capacitor/core") {
+		info.Evidence = append(info.Evidence, "@capacitor/core dependency in package.json")
+	}
+
+	// A Capacitor config is only created by `npx cap init`, while config.xml is often left behind
+	// after a Cordova to Capacitor migration, so the Capacitor config takes precedence.
+	switch {
+	case hasCapacitorConfig:
+		info.Integration = Capacitor
+	case hasConfigXML:
+		info.Integration = Cordova
+	case capacitorIntegration || pkg.has("@capacitor/core"):
+		info.Integration = Capacitor
+	case cordovaIntegration:
+		info.Integration = Cordova
+	default:
+		info.Integration = WebOnly
+	}
+
+	info.Framework = detectFramework(ionicConf.Type, pkg)
+	if !hasIonicConfig && !hasPackageJSON {
+		return info, fmt.Errorf("neither ionic.config.json nor package.json found in %s, make sure the working directory points to the Ionic project's root", projectDir)
+	}
+
+	return info, nil
+}
+
+func detectFramework(projectType string, pkg packageJSON) Framework {
+	switch {
+	case strings.Contains(projectType, "angular"):
+		return Angular
+	case strings.Contains(projectType, "react"):
+		return React
+	case strings.Contains(projectType, "vue"):
+		return Vue
+	case pkg.has("@angular/core"):
+		return Angular
+	case pkg.has("react"):
+		return React
+	case pkg.has("vue"):
+		return Vue
+	}
+	return Unknown
+}
+
+func readJSON(pth string, v interface{}) (bool, error) {
+	content, err := os.ReadFile(pth)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return true, fmt.Errorf("failed to parse %s: %s", pth, err)
+	}
+	return true, nil
+}
+
+func isFileExists(pth string) (bool, error) {
+	info, err := os.Stat(pth)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
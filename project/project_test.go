@@ -0,0 +1,161 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Detect(t *testing.T) {
+	tests := []struct {
+		name            string
+		files           map[string]string
+		wantIntegration Integration
+		wantFramework   Framework
+		wantErr         bool
+	}{
+		{
+			name: "cordova angular",
+			files: map[string]string{
+				"ionic.config.json": `{"name": "app", "type": "angular", "integrations": {"cordova": {}}}`,
+				"config.xml":        `<widget id="io.ionic.starter"></widget>`,
+				"package.json":      `{"dependencies": {"@angular/core": "^15.0.0"}}`,
+			},
+			wantIntegration: Cordova,
+			wantFramework:   Angular,
+		},
+		{
+			name: "capacitor react",
+			files: map[string]string{
+				"ionic.config.json":   `{"name": "app", "type": "react", "integrations": {"capacitor": {}}}`,
+				"capacitor.config.ts": `export default { appId: 'io.ionic.starter' };`,
+				"package.json":        `{"dependencies": {"@capacitor/core": "5.0.0", "react": "18.0.0"}}`,
+			},
+			wantIntegration: Capacitor,
+			wantFramework:   React,
+		},
+		{
+			name: "migrated to capacitor with config.xml left behind",
+			files: map[string]string{
+				"config.xml":            `<widget id="io.ionic.starter"></widget>`,
+				"capacitor.config.json": `{"appId": "io.ionic.starter"}`,
+				"package.json":          `{"dependencies": {"vue": "3.0.0"}}`,
+			},
+			wantIntegration: Capacitor,
+			wantFramework:   Vue,
+		},
+		{
+			name: "capacitor dependency without config",
+			files: map[string]string{
+				"package.json": `{"devDependencies": {"@capacitor/core": "5.0.0"}}`,
+			},
+			wantIntegration: Capacitor,
+			wantFramework:   Unknown,
+		},
+		{
+			name: "web only",
+			files: map[string]string{
+				"ionic.config.json": `{"name": "app", "type": "vue"}`,
+				"package.json":      `{}`,
+			},
+			wantIntegration: WebOnly,
+			wantFramework:   Vue,
+		},
+		{
+			name:    "not an ionic project",
+			files:   map[string]string{},
+			wantErr: true,
+		},
+		{
+			name: "invalid package.json",
+			files: map[string]string{
+				"package.json": `{`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			}
+
+			got, err := Detect(dir)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantIntegration, got.Integration)
+			require.Equal(t, tt.wantFramework, got.Framework)
+		})
+	}
+}
+
+func Test_ReadApp(t *testing.T) {
+	tests := []struct {
+		name        string
+		integration Integration
+		files       map[string]string
+		want        App
+		wantErr     bool
+	}{
+		{
+			name:        "cordova",
+			integration: Cordova,
+			files: map[string]string{
+				"config.xml": `<?xml version='1.0' encoding='utf-8'?>
+<widget id="io.ionic.starter" version="1.2.3" xmlns="http://www.w3.org/ns/widgets"><name>App</name></widget>`,
+			},
+			want: App{ID: "io.ionic.starter", Version: "1.2.3"},
+		},
+		{
+			name:        "capacitor",
+			integration: Capacitor,
+			files: map[string]string{
+				"capacitor.config.ts": `const config: CapacitorConfig = { appId: 'io.ionic.starter', appName: 'App', webDir: 'www' };`,
+				"package.json":        `{"version": "2.0.0"}`,
+			},
+			want: App{ID: "io.ionic.starter", Version: "2.0.0"},
+		},
+		{
+			name:        "capacitor without package.json",
+			integration: Capacitor,
+			files: map[string]string{
+				"capacitor.config.json": `{"appId": "io.ionic.starter"}`,
+			},
+			want: App{ID: "io.ionic.starter"},
+		},
+		{
+			name:        "missing config.xml",
+			integration: Cordova,
+			files:       map[string]string{},
+			wantErr:     true,
+		},
+		{
+			name:        "web only",
+			integration: WebOnly,
+			files:       map[string]string{},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+			}
+
+			got, err := ReadApp(dir, tt.integration)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/digest"
+	"github.com/bitrise-steplib/steps-ionic-archive/provenance"
+	"github.com/bitrise-steplib/steps-ionic-archive/redact"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const (
+	provenancePathEnvKey = "BITRISE_IONIC_PROVENANCE_PATH"
+	provenanceFileName   = "ionic-archive-provenance.intoto.json"
+)
+
+// lockfiles are the dependency lock files of the js package managers, the first existing one is recorded in the provenance
+var lockfiles = []string{"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml"}
+
+// provenanceExternalParameters are the step inputs, which define the build
+type provenanceExternalParameters struct {
+	Platform       string `json:"platform"`
+	Integration    string `json:"integration"`
+	Configuration  string `json:"configuration"`
+	Target         string `json:"target"`
+	BuildConfig    string `json:"build_config,omitempty"`
+	Options        string `json:"options,omitempty"`
+	IOSOptions     string `json:"ios_options,omitempty"`
+	AndroidOptions string `json:"android_options,omitempty"`
+	AndroidAppType string `json:"android_app_type"`
+	IonicVersion   string `json:"ionic_version,omitempty"`
+	CordovaVersion string `json:"cordova_version,omitempty"`
+}
+
+// provenanceInternalParameters describe the detected build environment and the executed commands (with the secrets redacted)
+type provenanceInternalParameters struct {
+	Integration    string   `json:"integration"`
+	PackageManager string   `json:"packageManager"`
+	IonicVersion   string   `json:"ionicVersion"`
+	CordovaVersion string   `json:"cordovaVersion,omitempty"`
+	Commands       []string `json:"commands"`
+}
+
+// buildProvenance collects the details of the build, which are recorded in the provenance attestation
+type buildProvenance struct {
+	configs   config
+	workDir   string
+	startedOn time.Time
+	recorder  *runner.Recorder
+	secrets   redact.Secrets
+	internal  provenanceInternalParameters
+}
+
+// newBuildProvenance starts collecting the details of the build, the commands have to be executed by the returned runner to be recorded
+func newBuildProvenance(configs config, workDir string, r runner.Runner) (*buildProvenance, runner.Runner) {
+	secrets := redact.NewSecrets(sensitiveValues(configs))
+	recorder := runner.NewRecorder(r, secrets.Redact, "envman")
+	return &buildProvenance{configs: configs, workDir: workDir, startedOn: time.Now(), recorder: recorder, secrets: secrets}, recorder
+}
+
+// write writes the provenance of the manifest's artifacts into the dir and returns its path
+func (p *buildProvenance) write(manifest artifactManifest, dir string, finishedOn time.Time) (string, error) {
+	var subjects []provenance.ResourceDescriptor
+	for _, file := range manifest.files() {
+		subjects = append(subjects, provenance.ResourceDescriptor{Name: filepath.Base(file.path), Digest: map[string]string{"sha256": file.sha256}})
+	}
+
+	internal := p.internal
+	internal.Commands = p.recorder.Commands()
+	statement := provenance.NewStatement(subjects, provenance.Predicate{
+		BuildDefinition: provenance.BuildDefinition{
+			BuildType: provenance.BuildType,
+			ExternalParameters: provenanceExternalParameters{
+				Platform:       p.configs.Platform,
+				Integration:    p.configs.Integration,
+				Configuration:  p.configs.Configuration,
+				Target:         p.configs.Target,
+				BuildConfig:    p.configs.BuildConfig,
+				Options:        p.secrets.Redact(p.configs.Options),
+				IOSOptions:     p.secrets.Redact(p.configs.IOSOptions),
+				AndroidOptions: p.secrets.Redact(p.configs.AndroidOptions),
+				AndroidAppType: p.configs.AndroidAppType,
+				IonicVersion:   p.configs.IonicVersion,
+				CordovaVersion: p.configs.CordovaVersion,
+			},
+			InternalParameters:   internal,
+			ResolvedDependencies: p.resolvedDependencies(),
+		},
+		RunDetails: provenance.RunDetails{
+			Builder: provenance.Builder{ID: provenance.BuilderID},
+			Metadata: provenance.Metadata{
+				InvocationID: p.configs.BuildURL,
+				StartedOn:    p.startedOn.UTC(),
+				FinishedOn:   finishedOn.UTC(),
+			},
+		},
+	})
+
+	content, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	pth := filepath.Join(dir, provenanceFileName)
+	if err := os.WriteFile(pth, append(content, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
+
+// resolvedDependencies returns the source (the git commit) and the dependency lock file of the project.
+// The commit is read from the repository, or from the git clone step's output if the work dir is not a git repository.
+func (p *buildProvenance) resolvedDependencies() []provenance.ResourceDescriptor {
+	var dependencies []provenance.ResourceDescriptor
+
+	// git is run by the wrapped runner, it is not a command of the build
+	commit, err := provenance.GitCommit(p.recorder.Runner, p.workDir)
+	if err != nil {
+		commit = p.configs.GitCommit
+	}
+	if commit != "" {
+		source := provenance.ResourceDescriptor{Name: "source", Digest: map[string]string{"gitCommit": commit}}
+		if p.configs.RepositoryURL != "" {
+			source.URI = "git+" + p.configs.RepositoryURL
+		}
+		dependencies = append(dependencies, source)
+	} else {
+		log.Warnf("Failed to read the git commit of the project, error: %s", err)
+	}
+
+	for _, name := range lockfiles {
+		if _, sum, err := digest.File(filepath.Join(p.workDir, name)); err == nil {
+			dependencies = append(dependencies, provenance.ResourceDescriptor{Name: name, Digest: map[string]string{"sha256": sum}})
+			break
+		}
+	}
+	return dependencies
+}
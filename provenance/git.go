@@ -0,0 +1,24 @@
+package provenance
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+// commitPattern matches a SHA-1 or a SHA-256 commit hash
+var commitPattern = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// GitCommit returns the commit checked out in the git repository, which contains the dir (git rev-parse HEAD)
+func GitCommit(r runner.Runner, dir string) (string, error) {
+	cmd := runner.New("git", "rev-parse", "HEAD").SetDir(dir)
+	out, err := r.RunAndReturnTrimmedCombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%s failed, output: %s, error: %s", cmd.PrintableCommandArgs(), out, err)
+	}
+	if !commitPattern.MatchString(out) {
+		return "", fmt.Errorf("%s returned an invalid commit: %s", cmd.PrintableCommandArgs(), out)
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package provenance
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+const commit = "0123456789abcdef0123456789abcdef01234567"
+
+func Test_GitCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{name: "commit", out: commit + "\n", want: commit},
+		{name: "sha-256 commit", out: commit + "0123456789abcdef01234567", want: commit + "0123456789abcdef01234567"},
+		{name: "not a git repository", out: "fatal: not a git repository (or any of the parent directories): .git", err: errors.New("exit status 128"), wantErr: true},
+		{name: "unborn branch", out: "HEAD", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := runner.NewFake(func(cmd *runner.Command) (string, error) {
+				return tt.out, tt.err
+			})
+
+			got, err := GitCommit(r, "/project")
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			records := r.Records()
+			require.Len(t, records, 1)
+			require.Equal(t, "git rev-parse HEAD", records[0].String())
+			require.Equal(t, "/project", records[0].Dir)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package provenance describes how the artifacts were built, as an in-toto statement with a SLSA provenance (v1) predicate
+package provenance
+
+import (
+	"time"
+)
+
+// Types of the statement and the predicate, the build type and the builder identify this step
+const (
+	StatementType = "https://in-toto.io/Statement/v1"
+	PredicateType = "https://slsa.dev/provenance/v1"
+	BuildType     = "https://github.com/bitrise-steplib/steps-ionic-archive/provenance/v1"
+	BuilderID     = "https://github.com/bitrise-steplib/steps-ionic-archive"
+)
+
+// Statement is an in-toto statement about the subjects (the artifacts)
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Predicate            `json:"predicate"`
+}
+
+// ResourceDescriptor identifies an artifact or a dependency by its digests (algorithm: hex encoded digest)
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate is the SLSA provenance of the subjects
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of the build, the parameters are defined by the build type
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   interface{}          `json:"externalParameters"`
+	InternalParameters   interface{}          `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// RunDetails describes the builder and the invocation of the build
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the step, which built the artifacts
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Metadata identifies the invocation (the CI build) and records when it started and finished
+type Metadata struct {
+	InvocationID string    `json:"invocationId,omitempty"`
+	StartedOn    time.Time `json:"startedOn"`
+	FinishedOn   time.Time `json:"finishedOn"`
+}
+
+// NewStatement returns a statement with the SLSA provenance predicate of the subjects
+func NewStatement(subjects []ResourceDescriptor, predicate Predicate) Statement {
+	if subjects == nil {
+		subjects = []ResourceDescriptor{}
+	}
+	return Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate:     predicate,
+	}
+}
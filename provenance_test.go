@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/provenance"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_buildProvenance_write(t *testing.T) {
+	workDir := t.TempDir()
+	deployDir := t.TempDir()
+	writeFiles(t, workDir, map[string]string{"yarn.lock": "lock"})
+	fake := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		if cmd.String() == "git rev-parse HEAD" {
+			return "0123456789abcdef0123456789abcdef01234567\n", nil
+		}
+		return "", nil
+	})
+
+	configs := config{Platform: "android", Integration: "auto", Configuration: "release", Target: "device", AndroidAppType: "apk", Username: "user@example.com", Password: "s3cr3t", RepositoryURL: "https://github.com/bitrise-io/ionic-app.git", BuildURL: "https://app.bitrise.io/build/1234"}
+	attestation, r := newBuildProvenance(configs, workDir, fake)
+	attestation.internal = provenanceInternalParameters{Integration: "cordova", PackageManager: "yarn", IonicVersion: "6.20.1", CordovaVersion: "12.0.0"}
+	require.NoError(t, r.Run(runner.New("ionic", "login", "user@example.com", "s3cr3t")))
+	require.NoError(t, r.Run(runner.New("ionic", "cordova", "build", "--release", "--device", "android")))
+	require.NoError(t, exportEnvironment(r, "BITRISE_APK_PATH", "app-release.apk"))
+
+	manifest := artifactManifest{Artifacts: []manifestArtifact{
+		{Type: "apk", DeployedPath: filepath.Join(deployDir, "app-release.apk"), SHA256: "1111"},
+		{Type: "dSYM", DeployedPath: filepath.Join(deployDir, "app.dSYM"), SHA256: "2222", Zip: &manifestZip{Path: filepath.Join(deployDir, "app.dSYM.zip"), SHA256: "3333"}},
+	}}
+	finishedOn := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	pth, err := attestation.write(manifest, deployDir, finishedOn)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(deployDir, "ionic-archive-provenance.intoto.json"), pth)
+
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+	var statement struct {
+		provenance.Statement
+		Predicate struct {
+			BuildDefinition struct {
+				provenance.BuildDefinition
+				ExternalParameters provenanceExternalParameters `json:"externalParameters"`
+				InternalParameters provenanceInternalParameters `json:"internalParameters"`
+			} `json:"buildDefinition"`
+			RunDetails provenance.RunDetails `json:"runDetails"`
+		} `json:"predicate"`
+	}
+	require.NoError(t, json.Unmarshal(content, &statement))
+
+	require.Equal(t, "https://in-toto.io/Statement/v1", statement.Type)
+	require.Equal(t, "https://slsa.dev/provenance/v1", statement.PredicateType)
+	require.Equal(t, []provenance.ResourceDescriptor{
+		{Name: "app-release.apk", Digest: map[string]string{"sha256": "1111"}},
+		{Name: "app.dSYM.zip", Digest: map[string]string{"sha256": "3333"}},
+	}, statement.Subject)
+
+	buildDefinition := statement.Predicate.BuildDefinition
+	require.Equal(t, provenanceExternalParameters{Platform: "android", Integration: "auto", Configuration: "release", Target: "device", AndroidAppType: "apk"}, buildDefinition.ExternalParameters)
+	require.Equal(t, provenanceInternalParameters{
+		Integration:    "cordova",
+		PackageManager: "yarn",
+		IonicVersion:   "6.20.1",
+		CordovaVersion: "12.0.0",
+		Commands:       []string{`ionic "login" "***" "***"`, `ionic "cordova" "build" "--release" "--device" "android"`},
+	}, buildDefinition.InternalParameters)
+	require.Equal(t, []provenance.ResourceDescriptor{
+		{Name: "source", URI: "git+https://github.com/bitrise-io/ionic-app.git", Digest: map[string]string{"gitCommit": "0123456789abcdef0123456789abcdef01234567"}},
+		{Name: "yarn.lock", Digest: map[string]string{"sha256": "0c030586945fe504b604ecc2e875c38ede400cd5cd73da9730302162e6b02c6f"}},
+	}, buildDefinition.ResolvedDependencies)
+
+	runDetails := statement.Predicate.RunDetails
+	require.Equal(t, "https://github.com/bitrise-steplib/steps-ionic-archive", runDetails.Builder.ID)
+	require.Equal(t, "https://app.bitrise.io/build/1234", runDetails.Metadata.InvocationID)
+	require.Equal(t, finishedOn, runDetails.Metadata.FinishedOn)
+	require.False(t, runDetails.Metadata.StartedOn.IsZero())
+	require.NotContains(t, string(content), "s3cr3t")
+}
+
+func Test_buildProvenance_resolvedDependencies(t *testing.T) {
+	notGitRepository := runner.NewFake(func(cmd *runner.Command) (string, error) {
+		return "fatal: not a git repository (or any of the parent directories): .git", errors.New("exit status 128")
+	})
+
+	// the commit of the git clone step is used, if the work dir is not a git repository
+	attestation, _ := newBuildProvenance(config{GitCommit: "89abcdef0123456789abcdef0123456789abcdef"}, t.TempDir(), notGitRepository)
+	require.Equal(t, []provenance.ResourceDescriptor{
+		{Name: "source", Digest: map[string]string{"gitCommit": "89abcdef0123456789abcdef0123456789abcdef"}},
+	}, attestation.resolvedDependencies())
+
+	attestation, _ = newBuildProvenance(config{}, t.TempDir(), notGitRepository)
+	require.Empty(t, attestation.resolvedDependencies())
+}
@@ -0,0 +1,168 @@
+// Package redact masks the secrets (sensitive inputs, passwords) in the step's log and in the output of the executed commands
+package redact
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+// Mask replaces the secrets
+const Mask = "***"
+
+// MinSecretLength is the length of the shortest masked parsed value, a shorter one would mask unrelated text all through the log
+const MinSecretLength = 6
+
+// pemBoundaryPattern matches the header and the footer line of a PEM block, which are not secret
+var pemBoundaryPattern = regexp.MustCompile(`^-----(BEGIN|END) [^-]*-----$`)
+
+// Secrets are the values, which are masked
+type Secrets struct {
+	values   []string
+	unmasked int
+}
+
+// NewSecrets returns the secrets to mask. The values of the inputs (like the sensitive step inputs) are always masked,
+// the parsed values (like the passwords read from a build config), which are shorter than MinSecretLength, are not.
+// Every line of a multiline value (like a PEM encoded key) is masked separately, as the output is redacted line by line,
+// except for the PEM header and footer lines.
+func NewSecrets(inputs []string, parsed []string) Secrets {
+	seen := map[string]bool{}
+	var secrets Secrets
+	add := func(values []string, minLength int) {
+		for _, value := range values {
+			for _, line := range strings.Split(value, "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" || pemBoundaryPattern.MatchString(line) || seen[line] {
+					continue
+				}
+				seen[line] = true
+				if len(line) < minLength {
+					secrets.unmasked++
+					continue
+				}
+				secrets.values = append(secrets.values, line)
+			}
+		}
+	}
+	add(inputs, 0)
+	add(parsed, MinSecretLength)
+
+	// a secret may contain an other one, the longer one is masked first
+	sort.SliceStable(secrets.values, func(i, j int) bool {
+		return len(secrets.values[i]) > len(secrets.values[j])
+	})
+	return secrets
+}
+
+// Unmasked returns the number of the parsed values, which are not masked as they are shorter than MinSecretLength
+func (s Secrets) Unmasked() int {
+	return s.unmasked
+}
+
+// Redact masks the secrets in the string
+func (s Secrets) Redact(str string) string {
+	for _, value := range s.values {
+		str = strings.ReplaceAll(str, value, Mask)
+	}
+	return str
+}
+
+// Writer masks the secrets in the written content, it is redacted line by line (\n and \r end a line),
+// so Flush has to be called to write the last, unterminated line.
+type Writer struct {
+	w       io.Writer
+	secrets Secrets
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewWriter returns a Writer, which writes the redacted content to w
+func NewWriter(w io.Writer, secrets Secrets) *Writer {
+	return &Writer{w: w, secrets: secrets}
+}
+
+// Write writes the redacted complete lines of the content and buffers the rest
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	end := bytes.LastIndexAny(w.buf, "\r\n")
+	if end == -1 {
+		return len(p), nil
+	}
+
+	lines := string(w.buf[:end+1])
+	w.buf = append([]byte{}, w.buf[end+1:]...)
+	if _, err := io.WriteString(w.w, w.secrets.Redact(lines)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the redacted buffered content
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	rest := string(w.buf)
+	w.buf = nil
+	_, err := io.WriteString(w.w, w.secrets.Redact(rest))
+	return err
+}
+
+// Runner is a runner.Runner, which masks the secrets in the output of the commands executed by the wrapped Runner
+type Runner struct {
+	runner.Runner
+
+	secrets Secrets
+}
+
+// NewRunner returns a Runner, which wraps the runner
+func NewRunner(r runner.Runner, secrets Secrets) *Runner {
+	return &Runner{Runner: r, secrets: secrets}
+}
+
+// Run runs the command with its stdout and stderr redacted
+func (r *Runner) Run(cmd *runner.Command) error {
+	redacted := *cmd
+	var writers []*Writer
+	if cmd.Stdout != nil {
+		stdout := NewWriter(cmd.Stdout, r.secrets)
+		redacted.Stdout = stdout
+		writers = append(writers, stdout)
+	}
+	if cmd.Stderr != nil {
+		if cmd.Stderr == cmd.Stdout {
+			redacted.Stderr = redacted.Stdout
+		} else {
+			stderr := NewWriter(cmd.Stderr, r.secrets)
+			redacted.Stderr = stderr
+			writers = append(writers, stderr)
+		}
+	}
+
+	err := r.Runner.Run(&redacted)
+	for _, w := range writers {
+		if flushErr := w.Flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+// RunAndReturnTrimmedCombinedOutput runs the command and returns its redacted output
+func (r *Runner) RunAndReturnTrimmedCombinedOutput(cmd *runner.Command) (string, error) {
+	out, err := r.Runner.RunAndReturnTrimmedCombinedOutput(cmd)
+	return r.secrets.Redact(out), err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	shellquote "github.com/kballard/go-shellquote"
+)
+
+// sensitiveValues returns the values, which are masked in the log and in the output of the commands:
+// the values of the sensitive inputs, and the parsed passwords of the build config and the password options of the build command.
+func sensitiveValues(configs config) (inputs []string, parsed []string) {
+	inputs = []string{string(configs.Username), string(configs.Password), string(configs.Token), string(configs.SigningKey)}
+
+	// the build config is read before switching to the work dir
+	buildConfigPth := configs.BuildConfig
+	if buildConfigPth != "" && !filepath.IsAbs(buildConfigPth) && configs.WorkDir != "" {
+		buildConfigPth = filepath.Join(configs.WorkDir, buildConfigPth)
+	}
+	if buildConfig, err := readBuildConfig(buildConfigPth); err == nil {
+		parsed = append(parsed, buildConfig.passwords()...)
+	}
+
+	for _, options := range []string{configs.Options, configs.IOSOptions, configs.AndroidOptions} {
+		parsed = append(parsed, optionPasswords(options)...)
+	}
+	return inputs, parsed
+}
+
+// passwords returns the keystore and the key passwords of every android build configuration
+func (c cordovaBuildConfig) passwords() []string {
+	var passwords []string
+	for _, signing := range c.Android {
+		passwords = append(passwords, signing.StorePassword, signing.Password)
+	}
+	return passwords
+}
+
+// optionPasswords returns the values of the password options (like --storePassword=<value> or --password <value>)
+func optionPasswords(options string) []string {
+	args, err := shellquote.Split(options)
+	if err != nil {
+		return nil
+	}
+
+	var passwords []string
+	for i, arg := range args {
+		if !strings.HasPrefix(arg, "-") || !strings.Contains(strings.ToLower(arg), "password") {
+			continue
+		}
+		if _, value, ok := strings.Cut(arg, "="); ok {
+			passwords = append(passwords, value)
+		} else if i+1 < len(args) {
+			passwords = append(passwords, args[i+1])
+		}
+	}
+	return passwords
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_optionPasswords(t *testing.T) {
+	tests := []struct {
+		options string
+		want    []string
+	}{
+		{options: "", want: nil},
+		{options: "--buildConfig=build.json --verbose", want: nil},
+		{options: "-- --storePassword=store-pass --password 'key pass'", want: []string{"store-pass", "key pass"}},
+		{options: "--keystorePassword", want: nil},
+		{options: "--password 'unterminated", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.options, func(t *testing.T) {
+			require.Equal(t, tt.want, optionPasswords(tt.options))
+		})
+	}
+}
+
+func Test_sensitiveValues(t *testing.T) {
+	workDir := t.TempDir()
+	writeFiles(t, workDir, map[string]string{
+		"build.json": `{"android": {"release": {"keystore": "release.keystore", "storePassword": "store-pass", "alias": "key0", "password": "key-pass"}}}`,
+	})
+
+	configs := config{
+		Username:    "user@example.com",
+		Token:       "ion_token",
+		BuildConfig: "build.json",
+		WorkDir:     workDir,
+		Options:     "--storePassword=option-pass",
+	}
+	inputs, parsed := sensitiveValues(configs)
+	require.Equal(t, []string{"user@example.com", "", "ion_token", ""}, inputs)
+	require.ElementsMatch(t, []string{"store-pass", "key-pass", "option-pass"}, parsed)
+
+	configs.BuildConfig = filepath.Join(workDir, "missing.json")
+	_, parsed = sensitiveValues(configs)
+	require.Equal(t, []string{"option-pass"}, parsed)
+}
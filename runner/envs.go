@@ -0,0 +1,35 @@
+package runner
+
+// ToolEnvs is a Runner, which appends environment variables (in KEY=value form) to the commands of a tool,
+// so that they are not set in the step's environment and are not inherited by the other commands.
+type ToolEnvs struct {
+	Runner
+
+	name string
+	envs []string
+}
+
+// NewToolEnvs returns a ToolEnvs runner, which wraps the runner and appends the envs to the commands with the name
+func NewToolEnvs(r Runner, name string, envs ...string) *ToolEnvs {
+	return &ToolEnvs{Runner: r, name: name, envs: envs}
+}
+
+// Run runs the command with the wrapped runner
+func (r *ToolEnvs) Run(cmd *Command) error {
+	return r.Runner.Run(r.withEnvs(cmd))
+}
+
+// RunAndReturnTrimmedCombinedOutput runs the command with the wrapped runner
+func (r *ToolEnvs) RunAndReturnTrimmedCombinedOutput(cmd *Command) (string, error) {
+	return r.Runner.RunAndReturnTrimmedCombinedOutput(r.withEnvs(cmd))
+}
+
+// withEnvs returns a copy of the tool's command with the envs, the caller's command is not modified
+func (r *ToolEnvs) withEnvs(cmd *Command) *Command {
+	if cmd.Name != r.name {
+		return cmd
+	}
+	withEnvs := *cmd
+	withEnvs.Envs = append(append([]string{}, cmd.Envs...), r.envs...)
+	return &withEnvs
+}
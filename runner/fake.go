@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// Fake is a Runner, which records the commands instead of executing them.
+// It is meant to be used in tests, Handler scripts the output and the result of the commands.
+type Fake struct {
+	// Handler returns the output and the error of the command, a nil Handler succeeds with no output
+	Handler func(cmd *Command) (string, error)
+
+	mu      sync.Mutex
+	records []Record
+}
+
+// Record is a command recorded by the Fake runner
+type Record struct {
+	Command
+	// Input is the content, which was passed to the command's stdin
+	Input string
+}
+
+// NewFake returns a Fake runner
+func NewFake(handler func(cmd *Command) (string, error)) *Fake {
+	return &Fake{Handler: handler}
+}
+
+// Run records the command and writes its scripted output to the command's stdout
+func (f *Fake) Run(cmd *Command) error {
+	out, err := f.handle(cmd)
+	if cmd.Stdout != nil && out != "" {
+		if _, writeErr := io.WriteString(cmd.Stdout, out+"\n"); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+// RunAndReturnTrimmedCombinedOutput records the command and returns its scripted output
+func (f *Fake) RunAndReturnTrimmedCombinedOutput(cmd *Command) (string, error) {
+	out, err := f.handle(cmd)
+	return strings.TrimSpace(out), err
+}
+
+// Records returns the recorded commands in the order of execution
+func (f *Fake) Records() []Record {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Record{}, f.records...)
+}
+
+// CommandStrings returns the recorded commands as strings (see Command.String)
+func (f *Fake) CommandStrings() []string {
+	var strs []string
+	for _, record := range f.Records() {
+		strs = append(strs, record.String())
+	}
+	return strs
+}
+
+func (f *Fake) handle(cmd *Command) (string, error) {
+	record := Record{Command: *cmd}
+	if cmd.Stdin != nil {
+		content, err := io.ReadAll(cmd.Stdin)
+		if err != nil {
+			return "", err
+		}
+		record.Input = string(content)
+		record.Stdin = nil
+	}
+
+	f.mu.Lock()
+	f.records = append(f.records, record)
+	f.mu.Unlock()
+
+	if f.Handler == nil {
+		return "", nil
+	}
+	handled := record.Command
+	handled.Stdin = strings.NewReader(record.Input)
+	return f.Handler(&handled)
+}
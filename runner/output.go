@@ -0,0 +1,28 @@
+package runner
+
+import "io"
+
+// Output is a Runner, which redirects the stdout and the stderr of the commands into a writer,
+// for example to separate the output of the commands executed concurrently.
+type Output struct {
+	Runner
+
+	w io.Writer
+}
+
+// NewOutput returns an Output runner, which wraps the runner and writes the output of the commands into w
+func NewOutput(r Runner, w io.Writer) *Output {
+	return &Output{Runner: r, w: w}
+}
+
+// Run runs the command with the wrapped runner, the command's stdout and stderr (if set) are replaced by the writer
+func (r *Output) Run(cmd *Command) error {
+	redirected := *cmd
+	if cmd.Stdout != nil {
+		redirected.Stdout = r.w
+	}
+	if cmd.Stderr != nil {
+		redirected.Stderr = r.w
+	}
+	return r.Runner.Run(&redirected)
+}
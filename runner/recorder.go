@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"sync"
+)
+
+// Recorder is a Runner, which records the commands executed by the wrapped Runner in a printable form.
+// The recorded commands are redacted, and the commands of the ignored tools (like envman) are not recorded.
+type Recorder struct {
+	Runner
+
+	redact  func(string) string
+	ignored []string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+// NewRecorder returns a Recorder, which wraps the runner and masks the secrets in the recorded commands with redact
+// (like redact.Secrets.Redact, so that the recorded commands are masked the same way as the log)
+func NewRecorder(r Runner, redact func(string) string, ignoredNames ...string) *Recorder {
+	return &Recorder{Runner: r, redact: redact, ignored: ignoredNames}
+}
+
+// Run records the command and runs it with the wrapped runner
+func (r *Recorder) Run(cmd *Command) error {
+	r.record(cmd)
+	return r.Runner.Run(cmd)
+}
+
+// RunAndReturnTrimmedCombinedOutput records the command and runs it with the wrapped runner
+func (r *Recorder) RunAndReturnTrimmedCombinedOutput(cmd *Command) (string, error) {
+	r.record(cmd)
+	return r.Runner.RunAndReturnTrimmedCombinedOutput(cmd)
+}
+
+// Commands returns the recorded commands in the order of execution
+func (r *Recorder) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string{}, r.commands...)
+}
+
+func (r *Recorder) record(cmd *Command) {
+	for _, name := range r.ignored {
+		if cmd.Name == name {
+			return
+		}
+	}
+
+	printable := cmd.PrintableCommandArgs()
+	if r.redact != nil {
+		printable = r.redact(printable)
+	}
+
+	r.mu.Lock()
+	r.commands = append(r.commands, printable)
+	r.mu.Unlock()
+}
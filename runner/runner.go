@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/command"
+)
+
+// Command describes an external command, which is executed by a Runner
+type Command struct {
+	Name   string
+	Args   []string
+	Dir    string
+	Envs   []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// New returns a command model
+func New(name string, args ...string) *Command {
+	return &Command{Name: name, Args: args}
+}
+
+// FromModel returns a command model from a go-utils command model, for commands created by shared step packages
+func FromModel(model *command.Model) *Command {
+	cmd := model.GetCmd()
+	c := New(cmd.Args[0], cmd.Args[1:]...)
+	c.Dir = cmd.Dir
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+	return c
+}
+
+// SetDir sets the working directory of the command
+func (c *Command) SetDir(dir string) *Command {
+	c.Dir = dir
+	return c
+}
+
+// AppendEnvs appends environment variables (in KEY=value form) to the inherited environment of the command
+func (c *Command) AppendEnvs(envs ...string) *Command {
+	c.Envs = append(c.Envs, envs...)
+	return c
+}
+
+// SetStdin sets the stdin of the command
+func (c *Command) SetStdin(in io.Reader) *Command {
+	c.Stdin = in
+	return c
+}
+
+// SetStdout sets the stdout of the command
+func (c *Command) SetStdout(out io.Writer) *Command {
+	c.Stdout = out
+	return c
+}
+
+// SetStderr sets the stderr of the command
+func (c *Command) SetStderr(err io.Writer) *Command {
+	c.Stderr = err
+	return c
+}
+
+// Slice returns the command name followed by the arguments
+func (c *Command) Slice() []string {
+	return append([]string{c.Name}, c.Args...)
+}
+
+// PrintableCommandArgs returns the command in a printable form
+func (c *Command) PrintableCommandArgs() string {
+	return command.PrintableCommandArgs(false, c.Slice())
+}
+
+// String returns the command name followed by the unquoted arguments, used to match commands
+func (c *Command) String() string {
+	return strings.Join(c.Slice(), " ")
+}
+
+// Runner executes commands
+type Runner interface {
+	Run(cmd *Command) error
+	RunAndReturnTrimmedCombinedOutput(cmd *Command) (string, error)
+}
+
+// Default executes the commands as child processes
+type Default struct{}
+
+// NewDefault returns a Runner, which executes the commands as child processes
+func NewDefault() Runner {
+	return Default{}
+}
+
+// Run runs the command
+func (Default) Run(cmd *Command) error {
+	return toModel(cmd).Run()
+}
+
+// RunAndReturnTrimmedCombinedOutput runs the command and returns its trimmed stdout and stderr
+func (Default) RunAndReturnTrimmedCombinedOutput(cmd *Command) (string, error) {
+	return toModel(cmd).RunAndReturnTrimmedCombinedOutput()
+}
+
+func toModel(c *Command) *command.Model {
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+	model := command.NewWithCmd(cmd)
+	if len(c.Envs) > 0 {
+		model.AppendEnvs(c.Envs...)
+	}
+	return model
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/command"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FromModel(t *testing.T) {
+	model := command.New("npm", "install", "-g", "cordova@latest").SetDir("/workdir")
+
+	cmd := FromModel(model)
+	require.Equal(t, "npm", cmd.Name)
+	require.Equal(t, []string{"install", "-g", "cordova@latest"}, cmd.Args)
+	require.Equal(t, "/workdir", cmd.Dir)
+	require.Equal(t, `npm "install" "-g" "cordova@latest"`, cmd.PrintableCommandArgs())
+}
+
+func Test_Fake(t *testing.T) {
+	r := NewFake(func(cmd *Command) (string, error) {
+		if cmd.Name == "cordova" {
+			return "", errors.New("exit status 1")
+		}
+		return " 6.20.1\n", nil
+	})
+
+	out, err := r.RunAndReturnTrimmedCombinedOutput(New("ionic", "-v").SetStdin(strings.NewReader("Y")))
+	require.NoError(t, err)
+	require.Equal(t, "6.20.1", out)
+
+	var stdout bytes.Buffer
+	require.NoError(t, r.Run(New("ionic", "build").SetStdout(&stdout)))
+	require.Equal(t, " 6.20.1\n\n", stdout.String())
+
+	require.EqualError(t, r.Run(New("cordova", "build")), "exit status 1")
+
+	require.Equal(t, []string{"ionic -v", "ionic build", "cordova build"}, r.CommandStrings())
+	require.Equal(t, "Y", r.Records()[0].Input)
+}
+
+func Test_Recorder(t *testing.T) {
+	fake := NewFake(nil)
+	r := NewRecorder(fake, func(s string) string {
+		return strings.NewReplacer("user@example.com", "***", "s3cr3t", "***").Replace(s)
+	}, "envman")
+
+	require.NoError(t, r.Run(New("ionic", "login", "user@example.com", "s3cr3t")))
+	require.NoError(t, r.Run(New("envman", "add", "--key", "BITRISE_IPA_PATH")))
+	_, err := r.RunAndReturnTrimmedCombinedOutput(New("ionic", "cordova", "build", "--release"))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{`ionic "login" "***" "***"`, `ionic "cordova" "build" "--release"`}, r.Commands())
+	// the wrapped runner executes every command
+	require.Equal(t, []string{"ionic login user@example.com s3cr3t", "envman add --key BITRISE_IPA_PATH", "ionic cordova build --release"}, fake.CommandStrings())
+}
+
+func Test_ToolEnvs(t *testing.T) {
+	fake := NewFake(nil)
+	r := NewToolEnvs(fake, "ionic", "IONIC_TOKEN=token")
+
+	build := New("ionic", "build").AppendEnvs("CI=true")
+	require.NoError(t, r.Run(build))
+	_, err := r.RunAndReturnTrimmedCombinedOutput(New("npm", "install"))
+	require.NoError(t, err)
+
+	records := fake.Records()
+	require.Equal(t, []string{"CI=true", "IONIC_TOKEN=token"}, records[0].Envs)
+	require.Empty(t, records[1].Envs)
+	// the caller's command is not modified
+	require.Equal(t, []string{"CI=true"}, build.Envs)
+}
+
+func Test_Output(t *testing.T) {
+	fake := NewFake(func(cmd *Command) (string, error) {
+		return "output of " + cmd.String(), nil
+	})
+	var out bytes.Buffer
+	r := NewOutput(fake, &out)
+
+	var stdout bytes.Buffer
+	build := New("ionic", "build").SetStdout(&stdout).SetStderr(&stdout)
+	require.NoError(t, r.Run(build))
+	require.NoError(t, r.Run(New("ionic", "prepare")))
+
+	require.Equal(t, "output of ionic build\n", out.String())
+	require.Empty(t, stdout.String())
+	// the caller's command is not modified
+	require.Equal(t, &stdout, build.Stdout)
+}
@@ -0,0 +1,113 @@
+// Package signing signs the artifact manifest and the checksum file with an ed25519 key,
+// the detached signatures can be verified with the public key of the CI without any external signing service.
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignatureExt is the extension of the detached signature written next to the signed file
+const SignatureExt = ".sig"
+
+// ParsePrivateKey parses an ed25519 private key, either a PEM encoded PKCS #8 key (openssl genpkey -algorithm ed25519)
+// or a base64 encoded 32 byte seed or 64 byte private key (the seed followed by the public key).
+func ParsePrivateKey(value string) (ed25519.PrivateKey, error) {
+	value = strings.TrimSpace(value)
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("not an ed25519 key: %T", key)
+		}
+		return privateKey, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("neither a PEM nor a base64 encoded key")
+	}
+	switch len(data) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(data), nil
+	case ed25519.PrivateKeySize:
+		// ed25519.Sign uses the public half as is, with a mismatching one the signatures wouldn't verify with the printed public key
+		privateKey := ed25519.NewKeyFromSeed(data[:ed25519.SeedSize])
+		if !bytes.Equal(privateKey, data) {
+			return nil, errors.New("the public key of the private key doesn't match its seed")
+		}
+		return privateKey, nil
+	}
+	return nil, fmt.Errorf("invalid key length: %d bytes", len(data))
+}
+
+// ParsePublicKey parses an ed25519 public key, either a PEM encoded PKIX key (openssl pkey -pubout) or a base64 encoded 32 byte key
+func ParsePublicKey(value string) (ed25519.PublicKey, error) {
+	value = strings.TrimSpace(value)
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("not an ed25519 key: %T", key)
+		}
+		return publicKey, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("neither a PEM nor a base64 encoded key")
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid key length: %d bytes", len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// Sign writes the base64 encoded signature of the file next to it (<file>.sig) and returns the path of the signature
+func Sign(key ed25519.PrivateKey, pth string) (string, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return "", err
+	}
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, content))
+	signaturePth := pth + SignatureExt
+	if err := os.WriteFile(signaturePth, []byte(signature+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return signaturePth, nil
+}
+
+// Verify checks the detached signature (written by Sign) of the file with the public key
+func Verify(key ed25519.PublicKey, pth, signaturePth string) error {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return err
+	}
+	encodedSignature, err := os.ReadFile(signaturePth)
+	if err != nil {
+		return err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSignature)))
+	if err != nil {
+		return fmt.Errorf("invalid signature: %s", err)
+	}
+	if !ed25519.Verify(key, content, signature) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
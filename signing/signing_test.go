@@ -0,0 +1,108 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodePEM(t *testing.T, typ string, key interface{}) string {
+	var der []byte
+	var err error
+	if typ == "PUBLIC KEY" {
+		der, err = x509.MarshalPKIXPublicKey(key)
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+}
+
+func Test_ParseKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	mismatchingKey := append(append([]byte{}, privateKey.Seed()...), otherPublicKey...)
+
+	privateKeyTests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "PEM", value: encodePEM(t, "PRIVATE KEY", privateKey)},
+		{name: "base64 seed", value: base64.StdEncoding.EncodeToString(privateKey.Seed())},
+		{name: "base64 private key", value: " " + base64.StdEncoding.EncodeToString(privateKey) + "\n"},
+		{name: "mismatching public key", value: base64.StdEncoding.EncodeToString(mismatchingKey), wantErr: true},
+		{name: "not an ed25519 key", value: encodePEM(t, "PRIVATE KEY", ecdsaKey), wantErr: true},
+		{name: "invalid length", value: base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{name: "not encoded", value: "secret key", wantErr: true},
+	}
+	for _, tt := range privateKeyTests {
+		t.Run("private key: "+tt.name, func(t *testing.T) {
+			got, err := ParsePrivateKey(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, privateKey, got)
+		})
+	}
+
+	publicKeyTests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "PEM", value: encodePEM(t, "PUBLIC KEY", publicKey)},
+		{name: "base64", value: base64.StdEncoding.EncodeToString(publicKey)},
+		{name: "not an ed25519 key", value: encodePEM(t, "PUBLIC KEY", &ecdsaKey.PublicKey), wantErr: true},
+		{name: "invalid length", value: base64.StdEncoding.EncodeToString(privateKey), wantErr: true},
+	}
+	for _, tt := range publicKeyTests {
+		t.Run("public key: "+tt.name, func(t *testing.T) {
+			got, err := ParsePublicKey(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, publicKey, got)
+		})
+	}
+}
+
+func Test_SignAndVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	pth := filepath.Join(t.TempDir(), "SHA256SUMS")
+	require.NoError(t, os.WriteFile(pth, []byte("1111  app-release.apk\n"), 0644))
+
+	signaturePth, err := Sign(privateKey, pth)
+	require.NoError(t, err)
+	require.Equal(t, pth+".sig", signaturePth)
+
+	require.NoError(t, Verify(publicKey, pth, signaturePth))
+	require.EqualError(t, Verify(otherPublicKey, pth, signaturePth), "signature mismatch")
+
+	require.NoError(t, os.WriteFile(pth, []byte("2222  app-release.apk\n"), 0644))
+	require.EqualError(t, Verify(publicKey, pth, signaturePth), "signature mismatch")
+
+	require.NoError(t, os.WriteFile(signaturePth, []byte("not a signature"), 0644))
+	require.Error(t, Verify(publicKey, pth, signaturePth))
+}
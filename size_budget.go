@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/breakdown"
+)
+
+// sizeUnits are the accepted units of the size budgets, the decimal and the binary prefixes are both supported
+var sizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"kib": 1024,
+	"mib": 1024 * 1024,
+	"gib": 1024 * 1024 * 1024,
+}
+
+// sizeBudgets are the maximum sizes of the exported archives by their type (ipa, apk, aab)
+type sizeBudgets map[string]int64
+
+// newSizeBudgets parses the size budget inputs, an empty input means no budget
+func newSizeBudgets(configs config) (sizeBudgets, error) {
+	budgets := sizeBudgets{}
+	for typ, value := range map[string]string{"ipa": configs.MaxIPASize, "apk": configs.MaxAPKSize, "aab": configs.MaxAABSize} {
+		if value == "" {
+			continue
+		}
+		size, err := parseSize(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max %s size (%s): %s", typ, value, err)
+		}
+		budgets[typ] = size
+	}
+	return budgets, nil
+}
+
+// parseSize parses a size, like 30MB, 25 MiB or 31457280 (bytes)
+func parseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(value)
+	}
+
+	number, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("not a positive number")
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(value[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit: %s", strings.TrimSpace(value[i:]))
+	}
+	return int64(number * float64(unit)), nil
+}
+
+// formatSize formats the size with decimal prefixes
+func formatSize(size int64) string {
+	switch {
+	case size >= 1000*1000*1000:
+		return fmt.Sprintf("%.1f GB", float64(size)/(1000*1000*1000))
+	case size >= 1000*1000:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1000*1000))
+	case size >= 1000:
+		return fmt.Sprintf("%.1f kB", float64(size)/1000)
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// formatBreakdown formats the categories of the report as a table
+func formatBreakdown(report breakdown.Report) string {
+	var lines []string
+	for _, category := range report.Categories {
+		lines = append(lines, fmt.Sprintf("  %-28s %10s (%d files, %s uncompressed)", category.Name, formatSize(category.Size), category.Files, formatSize(category.UncompressedSize)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reportSizes logs the size breakdown of the exported archives and records it in the artifact manifest,
+// an unreadable archive is reported, but it does not fail the step.
+func reportSizes(manifest *artifactManifest, deployedPaths []string) map[string]breakdown.Report {
+	reports := map[string]breakdown.Report{}
+	for _, pth := range deployedPaths {
+		report, err := breakdown.Archive(pth)
+		if err != nil {
+			log.Warnf("Failed to read the size breakdown of %s, error: %s", pth, err)
+			continue
+		}
+		reports[pth] = report
+		manifest.setBreakdown(pth, report)
+
+		log.Printf("%s: %s", filepath.Base(pth), formatSize(report.Size))
+		log.Printf("%s", formatBreakdown(report))
+	}
+	return reports
+}
+
+// violations returns the archives, which exceed the budget of their type, with their size breakdown
+func (b sizeBudgets) violations(reports map[string]breakdown.Report, deployedPaths []string) []string {
+	var violations []string
+	for _, pth := range deployedPaths {
+		report, ok := reports[pth]
+		if !ok {
+			continue
+		}
+		typ, _ := artifactType(pth)
+		budget, ok := b[typ]
+		if !ok || report.Size <= budget {
+			continue
+		}
+
+		violation := fmt.Sprintf("%s is %s, which exceeds the %s size budget of %s:\n%s", filepath.Base(pth), formatSize(report.Size), typ, formatSize(budget), formatBreakdown(report))
+		log.Errorf("%s", violation)
+		violations = append(violations, violation)
+	}
+	return violations
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bitrise-steplib/steps-ionic-archive/breakdown"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "31457280", want: 31457280},
+		{value: "30MB", want: 30000000},
+		{value: "30 MiB", want: 31457280},
+		{value: "1.5 gb", want: 1500000000},
+		{value: "512kB", want: 512000},
+		{value: "0", wantErr: true},
+		{value: "MB", wantErr: true},
+		{value: "30 TB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSize(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_formatSize(t *testing.T) {
+	require.Equal(t, "999 B", formatSize(999))
+	require.Equal(t, "1.5 kB", formatSize(1500))
+	require.Equal(t, "31.5 MB", formatSize(31457280))
+	require.Equal(t, "2.0 GB", formatSize(2000000000))
+}
+
+func Test_newSizeBudgets(t *testing.T) {
+	budgets, err := newSizeBudgets(config{MaxAPKSize: "30MB", MaxAABSize: "150 MB"})
+	require.NoError(t, err)
+	require.Equal(t, sizeBudgets{"apk": 30000000, "aab": 150000000}, budgets)
+
+	_, err = newSizeBudgets(config{MaxIPASize: "large"})
+	require.EqualError(t, err, "invalid max ipa size (large): not a positive number")
+}
+
+func Test_sizeBudgets_violations(t *testing.T) {
+	reports := map[string]breakdown.Report{
+		"/deploy/app-release.apk": {
+			Size: 31000000,
+			Categories: []breakdown.Category{
+				{Name: breakdown.WebAssets, Size: 21000000, UncompressedSize: 40000000, Files: 120},
+				{Name: breakdown.Dex, Size: 10000000, UncompressedSize: 20000000, Files: 2},
+			},
+		},
+		"/deploy/app.ipa": {Size: 90000000},
+	}
+	budgets := sizeBudgets{"apk": 30000000}
+
+	got := budgets.violations(reports, []string{"/deploy/app-release.apk", "/deploy/app.ipa", "/deploy/missing.apk"})
+	require.Equal(t, []string{
+		"app-release.apk is 31.0 MB, which exceeds the apk size budget of 30.0 MB:\n" +
+			"  assets/www                      21.0 MB (120 files, 40.0 MB uncompressed)\n" +
+			"  dex                             10.0 MB (2 files, 20.0 MB uncompressed)",
+	}, got)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-ionic-archive/runner"
+)
+
+const (
+	timingsPathEnvKey = "BITRISE_IONIC_TIMINGS_PATH"
+	timingsFileName   = "ionic-archive-timings.json"
+)
+
+// The timed phases
+const (
+	timingDependencyInstall  = "dependency install"
+	timingVersionDetection   = "version detection"
+	timingPluginInstall      = "plugin install"
+	timingLogin              = "login"
+	timingPrepare            = "prepare"
+	timingWebBuild           = "web build"
+	timingArtifactCollection = "artifact collection"
+	timingCacheMarking       = "cache marking"
+	// timingPlatformBuildFormat is the phase of a platform's build, like build ios
+	timingPlatformBuildFormat = "build %s"
+)
+
+// phaseTiming is the duration of a finished phase
+type phaseTiming struct {
+	Name            string    `json:"name"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+
+	duration time.Duration
+}
+
+// buildTimings is the content of the timings file
+type buildTimings struct {
+	StartedAt       time.Time     `json:"started_at"`
+	FinishedAt      time.Time     `json:"finished_at"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	Phases          []phaseTiming `json:"phases"`
+}
+
+// phaseTimer measures the duration of the build phases.
+// The phases run one after the other are timed with enter and leave, the concurrent ones (like the platform builds) with start.
+// The methods of a nil phaseTimer do nothing.
+type phaseTimer struct {
+	now       func() time.Time
+	startedAt time.Time
+
+	mu     sync.Mutex
+	phases []phaseTiming
+	// finishCurrent finishes the phase entered last
+	finishCurrent func()
+}
+
+// newPhaseTimer returns a phaseTimer, which measures the total duration from now
+func newPhaseTimer(now func() time.Time) *phaseTimer {
+	return &phaseTimer{now: now, startedAt: now()}
+}
+
+// start starts timing the phase and returns the function, which finishes it
+func (t *phaseTimer) start(name string) func() {
+	if t == nil {
+		return func() {}
+	}
+
+	startedAt := t.now()
+	return func() {
+		duration := t.now().Sub(startedAt)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.phases = append(t.phases, phaseTiming{Name: name, StartedAt: startedAt.UTC(), DurationSeconds: duration.Seconds(), duration: duration})
+	}
+}
+
+// enter finishes the phase entered before and starts timing the phase
+func (t *phaseTimer) enter(name string) {
+	if t == nil {
+		return
+	}
+	t.leave()
+	finish := t.start(name)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finishCurrent = finish
+}
+
+// leave finishes the phase entered last, if it is not finished yet
+func (t *phaseTimer) leave() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	finish := t.finishCurrent
+	t.finishCurrent = nil
+	t.mu.Unlock()
+
+	if finish != nil {
+		finish()
+	}
+}
+
+// timings returns the finished phases in the order of their start
+func (t *phaseTimer) timings(finishedAt time.Time) buildTimings {
+	t.mu.Lock()
+	phases := append([]phaseTiming{}, t.phases...)
+	t.mu.Unlock()
+
+	sort.SliceStable(phases, func(i, j int) bool {
+		return phases[i].StartedAt.Before(phases[j].StartedAt)
+	})
+	return buildTimings{
+		StartedAt:       t.startedAt.UTC(),
+		FinishedAt:      finishedAt.UTC(),
+		DurationSeconds: finishedAt.Sub(t.startedAt).Seconds(),
+		Phases:          phases,
+	}
+}
+
+// summary returns the table of the phases' durations and their share of the total duration
+func (b buildTimings) summary() []string {
+	total := time.Duration(b.DurationSeconds * float64(time.Second))
+
+	lines := []string{fmt.Sprintf("%-24s %10s %6s", "Phase", "Duration", "Share")}
+	for _, phase := range b.Phases {
+		share := 0.0
+		if total > 0 {
+			share = float64(phase.duration) / float64(total) * 100
+		}
+		lines = append(lines, fmt.Sprintf("%-24s %10s %5.1f%%", phase.Name, formatDuration(phase.duration), share))
+	}
+	return append(lines, fmt.Sprintf("%-24s %10s", "total", formatDuration(total)))
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(100 * time.Millisecond).String()
+}
+
+// write writes the timings into the dir and returns its path
+func (b buildTimings) write(dir string) (string, error) {
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	pth := filepath.Join(dir, timingsFileName)
+	if err := os.WriteFile(pth, append(content, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return pth, nil
+}
+
+// reportTimings prints the summary of the phase timings, writes the timings file into the dir and exports its path.
+// It is called even if the build fails, a failed report does not fail the build.
+func reportTimings(r runner.Runner, timer *phaseTimer, dir string) {
+	// the phase of a failed build is finished by the report
+	timer.leave()
+	timings := timer.timings(timer.now())
+
+	fmt.Println()
+	log.Infof("Phase timings")
+	for _, line := range timings.summary() {
+		log.Printf("%s", line)
+	}
+
+	pth, err := timings.write(dir)
+	if err != nil {
+		log.Warnf("Failed to write the phase timings, error: %s", err)
+		return
+	}
+	if err := exportEnvironment(r, timingsPathEnvKey, pth); err != nil {
+		log.Warnf("Failed to export the phase timings path, error: %s", err)
+		return
+	}
+	log.Donef("The phase timings path is now available in the Environment Variable: %s (value: %s)", timingsPathEnvKey, pth)
+}
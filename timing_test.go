@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns a time, which is advanced by step on every call
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func Test_phaseTimer(t *testing.T) {
+	t.Run("phases", func(t *testing.T) {
+		timer := newPhaseTimer(fakeClock(time.Second))
+		timer.enter(timingVersionDetection)
+		timer.enter(timingPrepare)
+		timer.leave()
+		finishIOS := timer.start("build ios")
+		finishAndroid := timer.start("build android")
+		finishAndroid()
+		finishIOS()
+		// leaving an already finished phase does nothing
+		timer.enter(timingArtifactCollection)
+		timer.leave()
+		timer.leave()
+
+		timings := timer.timings(timer.now())
+		require.Equal(t, 11.0, timings.DurationSeconds)
+		var names []string
+		var durations []float64
+		for _, phase := range timings.Phases {
+			names = append(names, phase.Name)
+			durations = append(durations, phase.DurationSeconds)
+		}
+		require.Equal(t, []string{"version detection", "prepare", "build ios", "build android", "artifact collection"}, names)
+		require.Equal(t, []float64{1, 1, 3, 1, 1}, durations)
+
+		require.Equal(t, []string{
+			"Phase                      Duration  Share",
+			"version detection                1s   9.1%",
+			"prepare                          1s   9.1%",
+			"build ios                        3s  27.3%",
+			"build android                    1s   9.1%",
+			"artifact collection              1s   9.1%",
+			"total                           11s",
+		}, timings.summary())
+	})
+
+	t.Run("nil timer", func(t *testing.T) {
+		var timer *phaseTimer
+		timer.enter(timingLogin)
+		timer.start("build ios")()
+		timer.leave()
+	})
+}
+
+func Test_buildTimings_write(t *testing.T) {
+	timer := newPhaseTimer(fakeClock(1500 * time.Millisecond))
+	timer.enter(timingDependencyInstall)
+	timer.leave()
+
+	dir := t.TempDir()
+	pth, err := timer.timings(timer.now()).write(dir)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "ionic-archive-timings.json"), pth)
+
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+	var timings map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &timings))
+	require.Equal(t, map[string]interface{}{
+		"started_at":       "2024-05-01T12:00:01.5Z",
+		"finished_at":      "2024-05-01T12:00:06Z",
+		"duration_seconds": 4.5,
+		"phases": []interface{}{
+			map[string]interface{}{
+				"name":             "dependency install",
+				"started_at":       "2024-05-01T12:00:03Z",
+				"duration_seconds": 1.5,
+			},
+		},
+	}, timings)
+}